@@ -0,0 +1,224 @@
+package main
+
+import (
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/net/html"
+)
+
+// Act holds metadata of a legal act published on the journal page.
+type Act struct {
+	Title string `json:"title"`
+	// Type is the kind of the act, e.g. Ustawa, Rozporządzenie or Obwieszczenie.
+	Type string `json:"type,omitempty"`
+	// Authority is the issuing authority as it appears in the title (genitive case).
+	Authority string `json:"authority,omitempty"`
+	// Institutions are dictionary names of the issuing authorities, e.g. "Ministra Finansów".
+	Institutions   []string          `json:"institutions,omitempty"`
+	Journal        string            `json:"journal,omitempty"`
+	Year           int               `json:"year,omitempty"`
+	Nr             int               `json:"nr,omitempty"`
+	Pos            int               `json:"pos,omitempty"`
+	Signed         *date             `json:"signed,omitempty"`
+	Announced      *date             `json:"announced,omitempty"`
+	EntryIntoForce *date             `json:"entry_into_force,omitempty"`
+	Status         string            `json:"status,omitempty"`
+	Files          []string          `json:"files,omitempty"`
+	Related        []string          `json:"related,omitempty"`
+	Fields         map[string]string `json:"fields,omitempty"`
+}
+
+// date is a calendar day, acts are published in Polish time so there is no time zone.
+type date struct {
+	time.Time
+}
+
+func (d date) MarshalJSON() ([]byte, error) {
+	return []byte(`"` + d.Format(time.DateOnly) + `"`), nil
+}
+
+func (d *date) UnmarshalJSON(b []byte) error {
+	t, err := time.Parse(`"`+time.DateOnly+`"`, string(b))
+	d.Time = t
+	return err
+}
+
+func parseDate(s string) *date {
+	t, err := time.Parse(time.DateOnly, strings.TrimSpace(s))
+	if err != nil {
+		return nil
+	}
+	return &date{t}
+}
+
+var months = map[string]time.Month{
+	"stycznia":     time.January,
+	"lutego":       time.February,
+	"marca":        time.March,
+	"kwietnia":     time.April,
+	"maja":         time.May,
+	"czerwca":      time.June,
+	"lipca":        time.July,
+	"sierpnia":     time.August,
+	"września":     time.September,
+	"października": time.October,
+	"listopada":    time.November,
+	"grudnia":      time.December,
+}
+
+// parsePolishDate parses dates like "1 grudnia 2020".
+func parsePolishDate(s string) *date {
+	parts := strings.Fields(s)
+	if len(parts) != 3 {
+		return nil
+	}
+	day, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return nil
+	}
+	month, ok := months[strings.ToLower(parts[1])]
+	if !ok {
+		return nil
+	}
+	year, err := strconv.Atoi(parts[2])
+	if err != nil {
+		return nil
+	}
+	return &date{time.Date(year, month, day, 0, 0, 0, 0, time.UTC)}
+}
+
+var titleRegexp = regexp.MustCompile(`^(\S+)\s+(.*?)\s*z dnia (\d{1,2} \S+ \d{4}) r\.`)
+
+// parseTitle extracts the act type, issuing authority and signing date from the title.
+func (a *Act) parseTitle() {
+	m := titleRegexp.FindStringSubmatch(a.Title)
+	if m == nil {
+		if fields := strings.Fields(a.Title); len(fields) > 0 {
+			a.Type = fields[0]
+		}
+		return
+	}
+	a.Type = m[1]
+	if a.Authority == "" {
+		a.Authority = m[2]
+	}
+	if a.Signed == nil {
+		a.Signed = parsePolishDate(m[3])
+	}
+}
+
+// parseActPage parses the act page from dziennikustaw.gov.pl, the returned act has no title when the page is missing.
+func parseActPage(body io.Reader, base string) Act {
+	act := Act{Fields: map[string]string{}}
+	z := html.NewTokenizer(body)
+
+	var (
+		inTitle bool
+		inRow   bool
+		cells   []string
+		links   []string
+		text    strings.Builder
+	)
+	for {
+		tt := z.Next()
+		switch tt {
+		case html.ErrorToken:
+			act.Title = strings.TrimSpace(act.Title)
+			act.parseTitle()
+			if len(act.Fields) == 0 {
+				act.Fields = nil
+			}
+			return act
+		case html.TextToken:
+			if inTitle {
+				act.Title += string(z.Text())
+			} else if inRow {
+				text.Write(z.Text())
+			}
+		case html.StartTagToken, html.SelfClosingTagToken:
+			name, hasAttr := z.TagName()
+			switch string(name) {
+			case "h2":
+				inTitle = act.Title == ""
+			case "tr":
+				inRow, cells, links = true, nil, nil
+				text.Reset()
+			case "td":
+				text.Reset()
+			case "a":
+				for hasAttr && inRow {
+					var key, val []byte
+					key, val, hasAttr = z.TagAttr()
+					if string(key) == "href" {
+						links = append(links, absoluteUrl(base, string(val)))
+					}
+				}
+			}
+		case html.EndTagToken:
+			name, _ := z.TagName()
+			switch string(name) {
+			case "h2":
+				inTitle = false
+			case "td":
+				if inRow {
+					cells = append(cells, strings.Join(strings.Fields(text.String()), " "))
+				}
+			case "tr":
+				act.addRow(cells, links)
+				inRow = false
+			}
+		}
+	}
+}
+
+func absoluteUrl(base, href string) string {
+	if strings.HasPrefix(href, "/") {
+		return base + href
+	}
+	return href
+}
+
+func (a *Act) addRow(cells []string, links []string) {
+	if len(cells) == 0 {
+		return
+	}
+	label := strings.TrimSuffix(cells[0], ":")
+	value := strings.Join(cells[1:], " ")
+	if value != "" {
+		a.Fields[label] = value
+	}
+	switch strings.ToLower(label) {
+	case "data ogłoszenia":
+		a.Announced = parseDate(value)
+	case "nazwa dziennika":
+		a.Journal = value
+	case "rok":
+		a.Year, _ = strconv.Atoi(value)
+	case "numer", "nr":
+		a.Nr, _ = strconv.Atoi(value)
+	case "pozycja":
+		a.Pos, _ = strconv.Atoi(value)
+	case "status", "status aktu":
+		a.Status = value
+	case "data wejścia w życie":
+		a.EntryIntoForce = parseDate(value)
+	case "data wydania", "data aktu":
+		a.Signed = parseDate(value)
+	case "organ wydający":
+		a.Authority = value
+	}
+	for _, link := range links {
+		if strings.HasSuffix(strings.ToLower(link), ".pdf") {
+			a.Files = append(a.Files, link)
+			if m := pdfNameRegexp.FindStringSubmatch(link); m != nil && a.Nr == 0 {
+				a.Nr, _ = strconv.Atoi(m[2])
+			}
+		} else {
+			a.Related = append(a.Related, link)
+		}
+	}
+}
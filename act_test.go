@@ -0,0 +1,97 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"os"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "update golden files")
+
+func Test_parseActPage(t *testing.T) {
+	t.Parallel()
+	for _, name := range []string{"sample", "404"} {
+		name := name
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			file, err := os.Open("testdata/" + name + ".html")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer file.Close()
+			got, err := json.MarshalIndent(parseActPage(file, url), "", "  ")
+			if err != nil {
+				t.Fatal(err)
+			}
+			got = append(got, '\n')
+			golden := "testdata/" + name + ".golden.json"
+			if *update {
+				if err := os.WriteFile(golden, got, 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, want) {
+				t.Errorf("parseActPage() =\n%s\nwant\n%s", got, want)
+			}
+		})
+	}
+}
+
+func Test_parseActPageOldAct(t *testing.T) {
+	t.Parallel()
+	page := `<h2>Konstytucja Rzeczypospolitej Polskiej z dnia 2 kwietnia 1997 r.</h2>
+<table><tr><td>Data ogłoszenia:</td><td>1997-07-16</td></tr>
+<tr><td>Status aktu:</td><td>obowiązujący</td></tr>
+<tr><td>Akty powiązane:</td><td><a href="/DU/2001/28/319">Dz.U. 2001 poz. 319</a></td></tr>
+<tr><td>Pobierz plik:</td><td><a href="/DU/1997/483/D1997078048301.pdf">plik</a></td></tr></table>`
+	act := parseActPage(strings.NewReader(page), url)
+	if act.Nr != 78 || act.Type != "Konstytucja" || act.Authority != "Rzeczypospolitej Polskiej" {
+		t.Errorf("act = %+v", act)
+	}
+	if act.Signed.Format("2006-01-02") != "1997-04-02" || act.Announced.Format("2006-01-02") != "1997-07-16" || act.Status != "obowiązujący" {
+		t.Errorf("act = %+v", act)
+	}
+	if len(act.Related) != 1 || act.Related[0] != "https://dziennikustaw.gov.pl/DU/2001/28/319" {
+		t.Errorf("related = %v", act.Related)
+	}
+	if len(act.Files) != 1 || act.Files[0] != "https://dziennikustaw.gov.pl/DU/1997/483/D1997078048301.pdf" {
+		t.Errorf("files = %v", act.Files)
+	}
+}
+
+func Test_parseTitle(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		title     string
+		typ       string
+		authority string
+		signed    string
+	}{
+		{title: "Ustawa z dnia 9 maja 1996 r. o wykonywaniu mandatu posła i senatora", typ: "Ustawa", signed: "1996-05-09"},
+		{title: "Obwieszczenie Marszałka Sejmu Rzeczypospolitej Polskiej z dnia 22 kwietnia 2026 r. w sprawie ogłoszenia jednolitego tekstu", typ: "Obwieszczenie", authority: "Marszałka Sejmu Rzeczypospolitej Polskiej", signed: "2026-04-22"},
+		{title: "Rozporządzenie Rady Ministrów z dnia 31 grudnia 2019 r. w sprawie", typ: "Rozporządzenie", authority: "Rady Ministrów", signed: "2019-12-31"},
+		{title: "Protokół w sprawie zmiany Umowy", typ: "Protokół"},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.title, func(t *testing.T) {
+			t.Parallel()
+			a := Act{Title: tt.title}
+			a.parseTitle()
+			signed := ""
+			if a.Signed != nil {
+				signed = a.Signed.Format("2006-01-02")
+			}
+			if a.Type != tt.typ || a.Authority != tt.authority || signed != tt.signed {
+				t.Errorf("parseTitle() = %q %q %q", a.Type, a.Authority, signed)
+			}
+		})
+	}
+}
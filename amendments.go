@@ -0,0 +1,269 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// relation is the kind of a link between acts.
+type relation string
+
+const (
+	relationAmends     relation = "amends"
+	relationRepeals    relation = "repeals"
+	relationImplements relation = "implements"
+)
+
+// relations lists relations in the order they are presented, with labels used in replies and in the history.
+var relations = []struct {
+	relation relation
+	// label is appended to the summary reply, e.g. "Zmienia: Dz.U. 2019 poz. 1234".
+	label string
+	// reverse describes the link from the cited act point of view.
+	reverse string
+}{
+	{relationAmends, "Zmienia", "amended by"},
+	{relationRepeals, "Uchyla", "repealed by"},
+	{relationImplements, "Na podstawie", "implemented by"},
+}
+
+// actLink is a directed edge from an act to the act it cites.
+type actLink struct {
+	Relation relation `json:"relation"`
+	// Act is the ELI of the cited act, e.g. "DU/2019/1234".
+	Act string `json:"act"`
+}
+
+const (
+	// linkContextBefore and linkContextAfter limit the text around a citation searched for the relation.
+	linkContextBefore = 300
+	linkContextAfter  = 120
+)
+
+var (
+	// relationBeforeRegexp matches phrases preceding the cited act, e.g. "Traci moc rozporządzenie … (Dz. U. poz. 1)".
+	relationBeforeRegexp = regexp.MustCompile(`(?i)(?P<repeals>trac[ią] moc|uchyla się (?:ustaw|rozporządze|zarządze|uchwał|obwieszcze))|(?P<amends>zmieniając[aey]?\b|zmienia się|wprowadza się (?:następujące )?zmian)|(?P<implements>na podstawie|w wykonaniu|w celu wykonania|w celu wdrożenia)`)
+	// relationAfterRegexp matches phrases following the cited act, e.g. "W ustawie … (Dz. U. poz. 1) wprowadza się następujące zmiany".
+	relationAfterRegexp = regexp.MustCompile(`(?i)^[^:;\n]*?(?:wprowadza się (?:następujące )?zmian|zmienia się|otrzymuje brzmienie|dodaje się|uchyla się)`)
+	// unitRegexp matches the beginning of an article or a paragraph, relations are stated separately in each of them.
+	unitRegexp = regexp.MustCompile(`(?:§|Art\.)\s*\d+[a-z]*\.\s`)
+	// citedDateRegexp matches the date of the cited act, citations without a year refer to the year of this date.
+	citedDateRegexp = regexp.MustCompile(`z dnia \d{1,2} \p{L}+ (\d{4}) r\.`)
+)
+
+// findLinks returns acts amended, repealed or implemented by the act with the text, other citations are ignored.
+// self is the ELI of the act and year is its publication year.
+func findLinks(self string, year int, text string) []actLink {
+	var result []actLink
+	seen := map[actLink]bool{}
+	for offset := 0; offset < len(text); {
+		loc := citationJournalRegexp.FindStringSubmatchIndex(text[offset:])
+		if loc == nil {
+			break
+		}
+		j := dziennikUstaw
+		if loc[6] >= 0 {
+			j = monitorPolski
+		}
+		start := offset + loc[0]
+		offset += loc[1]
+		cited, n := parseCitation(j, text[offset:], loc[2] >= 0)
+		offset += n
+		// citations in quoted new wording of amended provisions belong to the amended act
+		if len(cited) == 0 || strings.LastIndex(text[:start], "„") > strings.LastIndex(text[:start], "”") {
+			continue
+		}
+
+		before := text[max(0, start-linkContextBefore):start]
+		if units := unitRegexp.FindAllStringIndex(before, -1); len(units) > 0 {
+			before = before[units[len(units)-1][0]:]
+		}
+		after := text[offset:min(len(text), offset+linkContextAfter)]
+		rel, ok := linkRelation(before, after)
+		if !ok {
+			continue
+		}
+		for _, c := range cited {
+			if c.Year == 0 {
+				c.Year = citedYear(before, year)
+			}
+			link := actLink{Relation: rel, Act: c.eli()}
+			if c.Pos == 0 || link.Act == self || seen[link] {
+				continue
+			}
+			seen[link] = true
+			result = append(result, link)
+		}
+	}
+	return result
+}
+
+// linkRelation returns the relation stated around the citation, the closest phrase wins.
+func linkRelation(before, after string) (relation, bool) {
+	if relationAfterRegexp.MatchString(after) {
+		return relationAmends, true
+	}
+	matches := relationBeforeRegexp.FindAllStringSubmatchIndex(before, -1)
+	if len(matches) == 0 {
+		return "", false
+	}
+	last := matches[len(matches)-1]
+	for i, name := range relationBeforeRegexp.SubexpNames() {
+		if name != "" && last[2*i] >= 0 {
+			return relation(name), true
+		}
+	}
+	return "", false
+}
+
+// citedYear returns the year of the cited act from its date, acts published in the same year as they were
+// signed are cited without the year.
+func citedYear(before string, year int) int {
+	dates := citedDateRegexp.FindAllStringSubmatch(before, -1)
+	if len(dates) == 0 {
+		return year
+	}
+	y, _ := strconv.Atoi(dates[len(dates)-1][1])
+	return y
+}
+
+// withLinks appends acts linked to the act to its summary as long as the reply fits in a tweet.
+func withLinks(summary string, links []actLink) string {
+	result := summary
+	for _, r := range relations {
+		line := ""
+		for _, link := range links {
+			if link.Relation != r.relation {
+				continue
+			}
+			c, err := parseELI(link.Act)
+			if err != nil {
+				log.WithError(err).Warn("Invalid link")
+				continue
+			}
+			next := r.label + ": " + c.String()
+			if line != "" {
+				next = line + ", " + c.String()
+			}
+			if tweetLength(result+"\n"+next) > twitterMaxLength {
+				break
+			}
+			line = next
+		}
+		if line != "" {
+			result += "\n" + line
+		}
+	}
+	return result
+}
+
+// amendmentEvent is a link between acts as seen from the act the history is printed for.
+type amendmentEvent struct {
+	Relation relation
+	// Incoming is true when the other act links to this one, e.g. amends it.
+	Incoming bool
+	Other    citation
+	Entry    ledgerEntry
+}
+
+// amendmentGraph is built from links stored in the ledgers of all journals.
+type amendmentGraph struct {
+	entries  map[string]ledgerEntry
+	incoming map[string][]actLink
+}
+
+func newAmendmentGraph(ledgers []*ledger) *amendmentGraph {
+	g := &amendmentGraph{entries: map[string]ledgerEntry{}, incoming: map[string][]actLink{}}
+	for _, l := range ledgers {
+		for _, e := range l.entries {
+			from := citation{Journal: l.journal, Year: e.Year, Pos: e.Pos}.eli()
+			g.entries[from] = e
+			for _, link := range e.Links {
+				g.incoming[link.Act] = append(g.incoming[link.Act], actLink{Relation: link.Relation, Act: from})
+			}
+		}
+	}
+	return g
+}
+
+// history returns acts linked to the act, acts which changed it come first in publication order.
+func (g *amendmentGraph) history(eli string) []amendmentEvent {
+	var result []amendmentEvent
+	add := func(links []actLink, incoming bool) {
+		for _, link := range links {
+			c, err := parseELI(link.Act)
+			if err != nil {
+				continue
+			}
+			result = append(result, amendmentEvent{Relation: link.Relation, Incoming: incoming, Other: c, Entry: g.entries[link.Act]})
+		}
+	}
+	add(g.incoming[eli], true)
+	add(g.entries[eli].Links, false)
+	sort.SliceStable(result, func(i, j int) bool {
+		a, b := result[i], result[j]
+		if a.Incoming != b.Incoming {
+			return a.Incoming
+		}
+		if a.Other.Year != b.Other.Year {
+			return a.Other.Year < b.Other.Year
+		}
+		return a.Other.Pos < b.Other.Pos
+	})
+	return result
+}
+
+func (g *amendmentGraph) print(w io.Writer, eli string) error {
+	c, err := parseELI(eli)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(w, "%s %s\n", c, g.entries[eli].Title)
+	for _, e := range g.history(eli) {
+		label := string(e.Relation)
+		for _, r := range relations {
+			if r.relation == e.Relation && e.Incoming {
+				label = r.reverse
+			}
+		}
+		fmt.Fprintln(w, strings.TrimRight(fmt.Sprintf("  %-15s %s %s", label, e.Other, e.Entry.Title), " "))
+	}
+	return nil
+}
+
+func historyCommand(args []string) {
+	fs := flag.NewFlagSet("history", flag.ExitOnError)
+	code := fs.String("journal", dziennikUstaw.Code, "journal code, DU or MP")
+	year := fs.Int("year", 0, "year of the act (required)")
+	pos := fs.Int("pos", 0, "position of the act (required)")
+	fs.Parse(args)
+	j, err := journalByCode(*code)
+	if err != nil || *year == 0 || *pos == 0 {
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+		}
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	var ledgers []*ledger
+	for _, j := range journals {
+		l, err := j.openLedger()
+		if err != nil {
+			log.WithError(err).Fatal("Could not open ledger")
+		}
+		ledgers = append(ledgers, l)
+	}
+	eli := citation{Journal: j, Year: *year, Pos: *pos}.eli()
+	if err := newAmendmentGraph(ledgers).print(os.Stdout, eli); err != nil {
+		log.WithError(err).Fatal("Could not print history")
+	}
+}
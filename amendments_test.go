@@ -0,0 +1,104 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const amendingActText = `ROZPORZĄDZENIE MINISTRA FINANSÓW
+z dnia 3 lutego 2020 r.
+zmieniające rozporządzenie w sprawie zwolnień z obowiązku prowadzenia ewidencji
+Na podstawie art. 111 ust. 8 ustawy z dnia 11 marca 2004 r. o podatku od towarów i usług (Dz. U. z 2018 r. poz. 2174, z późn. zm.) zarządza się, co następuje:
+§ 1. W rozporządzeniu Ministra Finansów z dnia 28 grudnia 2019 r. w sprawie zwolnień z obowiązku prowadzenia ewidencji (Dz. U. poz. 2519) wprowadza się następujące zmiany:
+1) w § 2 ust. 1 otrzymuje brzmienie: „1. Zwalnia się podatników, o których mowa w ustawie z dnia 29 sierpnia 1997 r. – Ordynacja podatkowa (Dz. U. z 2019 r. poz. 900)”;
+§ 2. Traci moc rozporządzenie Ministra Finansów z dnia 20 grudnia 2018 r. w sprawie kas (Dz. U. poz. 2519 i 2520).
+§ 3. Rozporządzenie wchodzi w życie z dniem następującym po dniu ogłoszenia.
+1) Zmiany tekstu jednolitego wymienionej ustawy zostały ogłoszone w Dz. U. z 2019 r. poz. 1018 i 1495.`
+
+func Test_findLinks(t *testing.T) {
+	t.Parallel()
+	got := findLinks("DU/2020/200", 2020, amendingActText)
+	want := []actLink{
+		{Relation: relationImplements, Act: "DU/2018/2174"},
+		{Relation: relationAmends, Act: "DU/2019/2519"},
+		{Relation: relationRepeals, Act: "DU/2018/2519"},
+		{Relation: relationRepeals, Act: "DU/2018/2520"},
+	}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("findLinks() = %v\nwant %v", got, want)
+	}
+
+	if got := findLinks("DU/2020/1", 2020, "Rozporządzenie zmieniające rozporządzenie (Dz. U. poz. 1)"); len(got) != 0 {
+		t.Errorf("self citation returned: %v", got)
+	}
+}
+
+func Test_withLinks(t *testing.T) {
+	t.Parallel()
+	links := []actLink{
+		{Relation: relationImplements, Act: "DU/2018/2174"},
+		{Relation: relationAmends, Act: "DU/2019/2519"},
+		{Relation: relationAmends, Act: "MP/2019/5"},
+	}
+	want := "Podsumowanie\nZmienia: Dz.U. 2019 poz. 2519, M.P. 2019 poz. 5\nNa podstawie: Dz.U. 2018 poz. 2174"
+	if got := withLinks("Podsumowanie", links); got != want {
+		t.Errorf("withLinks() = %q, want %q", got, want)
+	}
+	if got := withLinks("Podsumowanie", nil); got != "Podsumowanie" {
+		t.Errorf("withLinks() = %q", got)
+	}
+
+	long := strings.Repeat("a", 250)
+	if got := withLinks(long, links); got != long+"\nZmienia: Dz.U. 2019 poz. 2519" {
+		t.Errorf("withLinks() = %q, the reply must fit in a tweet", got)
+	}
+}
+
+func Test_amendmentGraph(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	l, err := openLedger(filepath.Join(dir, ledgerFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range []ledgerEntry{
+		{Year: 2019, Pos: 2519, Title: "Rozporządzenie w sprawie ewidencji"},
+		{Year: 2021, Pos: 7, Title: "Rozporządzenie uchylające", Links: []actLink{{Relation: relationRepeals, Act: "DU/2019/2519"}}},
+		{Year: 2020, Pos: 200, Title: "Rozporządzenie zmieniające", Links: []actLink{
+			{Relation: relationImplements, Act: "DU/2018/2174"},
+			{Relation: relationAmends, Act: "DU/2019/2519"},
+		}},
+	} {
+		e.Status = statusArchived
+		if err := l.record(e); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var b bytes.Buffer
+	if err := newAmendmentGraph([]*ledger{l}).print(&b, "DU/2019/2519"); err != nil {
+		t.Fatal(err)
+	}
+	want := `Dz.U. 2019 poz. 2519 Rozporządzenie w sprawie ewidencji
+  amended by      Dz.U. 2020 poz. 200 Rozporządzenie zmieniające
+  repealed by     Dz.U. 2021 poz. 7 Rozporządzenie uchylające
+`
+	if b.String() != want {
+		t.Errorf("history =\n%s\nwant\n%s", b.String(), want)
+	}
+
+	b.Reset()
+	if err := newAmendmentGraph([]*ledger{l}).print(&b, "DU/2020/200"); err != nil {
+		t.Fatal(err)
+	}
+	want = `Dz.U. 2020 poz. 200 Rozporządzenie zmieniające
+  implements      Dz.U. 2018 poz. 2174
+  amends          Dz.U. 2019 poz. 2519 Rozporządzenie w sprawie ewidencji
+`
+	if b.String() != want {
+		t.Errorf("history =\n%s\nwant\n%s", b.String(), want)
+	}
+}
@@ -0,0 +1,523 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/gen2brain/go-fitz"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	defaultAPILimit = 100
+	maxAPILimit     = 1000
+	// renderedActs is how many acts have their page images kept.
+	renderedActs = 16
+)
+
+// archive reads acts from the ledgers of the journals, a ledger is read again when its file changes
+// so the API serves acts published by the bot running in another process.
+type archive struct {
+	journals []*journal
+	mu       sync.Mutex
+	ledgers  map[*journal]*ledger
+	versions map[*journal]fileVersion
+}
+
+// fileVersion tells if a ledger changed, the size changes even when the modification time is too coarse.
+type fileVersion struct {
+	modTime time.Time
+	size    int64
+}
+
+func newArchive(journals []*journal) *archive {
+	return &archive{journals: journals, ledgers: map[*journal]*ledger{}, versions: map[*journal]fileVersion{}}
+}
+
+func (a *archive) journal(code string) (*journal, bool) {
+	for _, j := range a.journals {
+		if strings.EqualFold(j.Code, code) {
+			return j, true
+		}
+	}
+	return nil, false
+}
+
+func (a *archive) ledger(j *journal) (*ledger, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	var version fileVersion
+	if info, err := os.Stat(j.Ledger); err == nil {
+		version = fileVersion{modTime: info.ModTime(), size: info.Size()}
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	if l, ok := a.ledgers[j]; ok && a.versions[j] == version {
+		return l, nil
+	}
+	l, err := j.openLedger()
+	if err != nil {
+		return nil, err
+	}
+	a.ledgers[j], a.versions[j] = l, version
+	return l, nil
+}
+
+// ready reports an error when a ledger cannot be read.
+func (a *archive) ready() error {
+	for _, j := range a.journals {
+		if _, err := a.ledger(j); err != nil {
+			return fmt.Errorf("could not read %s ledger: %w", j.Code, err)
+		}
+	}
+	return nil
+}
+
+// archivedAct is an act as returned by the API.
+type archivedAct struct {
+	ELI     string                `json:"eli"`
+	Journal string                `json:"journal"`
+	Year    int                   `json:"year"`
+	Nr      int                   `json:"nr,omitempty"`
+	Pos     int                   `json:"pos"`
+	Title   string                `json:"title"`
+	Status  actStatus             `json:"status"`
+	Meta    *Act                  `json:"metadata,omitempty"`
+	Summary string                `json:"summary,omitempty"`
+	Posts   map[string]targetPost `json:"posts,omitempty"`
+	Links   []actLink             `json:"links,omitempty"`
+	PageURL string                `json:"page_url"`
+	PDFURL  string                `json:"pdf_url"`
+	Pages   []string              `json:"pages,omitempty"`
+}
+
+func newArchivedAct(j *journal, e ledgerEntry) archivedAct {
+	c := citation{Journal: j, Year: e.Year, Pos: e.Pos}
+	act := archivedAct{
+		ELI:     c.eli(),
+		Journal: j.Code,
+		Year:    e.Year,
+		Nr:      e.Nr,
+		Pos:     e.Pos,
+		Title:   e.Title,
+		Status:  e.Status,
+		Meta:    e.Act,
+		Summary: e.Summary,
+		Posts:   e.Posts,
+		Links:   e.Links,
+		PageURL: j.actPageUrl(e.Year, e.Pos),
+		PDFURL:  j.pdfUrl(e.Year, e.Nr, e.Pos),
+	}
+	for i := 1; i <= e.Pages; i++ {
+		act.Pages = append(act.Pages, fmt.Sprintf("/api/acts/%s/pages/%d", c.eli(), i))
+	}
+	return act
+}
+
+// actFilter selects acts listed by the API.
+type actFilter struct {
+	Year      int
+	Date      string
+	Authority string
+	// institution is the dictionary name of the authority given in any grammatical case.
+	institution string
+}
+
+func (f actFilter) match(e ledgerEntry) bool {
+	if f.Year != 0 && e.Year != f.Year {
+		return false
+	}
+	if f.Date != "" && (e.Act == nil || e.Act.Announced == nil || e.Act.Announced.Format(time.DateOnly) != f.Date) {
+		return false
+	}
+	if f.Authority != "" && (e.Act == nil || !strings.Contains(strings.ToLower(e.Act.Authority), strings.ToLower(f.Authority)) &&
+		(f.institution == "" || !slices.Contains(e.Act.Institutions, f.institution))) {
+		return false
+	}
+	return true
+}
+
+type actList struct {
+	Total int           `json:"total"`
+	Acts  []archivedAct `json:"acts"`
+}
+
+// api serves the archive of acts as JSON.
+type api struct {
+	archive *archive
+	index   *searchIndex
+	// render returns page images of the act, they are not archived so they are rendered again from the PDF.
+	render func(ctx context.Context, j *journal, year, nr, pos int) ([][]byte, error)
+
+	mu        sync.Mutex
+	rendered  *pageCache
+	rendering map[string]*renderCall
+}
+
+// renderCall is a render in progress shared by requests for pages of the same act.
+type renderCall struct {
+	done  chan struct{}
+	pages [][]byte
+	err   error
+}
+
+func newAPI(a *archive, idx *searchIndex) *api {
+	return &api{archive: a, index: idx, render: renderPages, rendered: newPageCache(renderedActs), rendering: map[string]*renderCall{}}
+}
+
+func (s *api) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/acts", s.listActs)
+	mux.HandleFunc("GET /api/acts/{journal}/{year}/{pos}", s.getAct)
+	mux.HandleFunc("GET /api/acts/{journal}/{year}/{pos}/pages/{page}", s.getPage)
+	mux.HandleFunc("GET /api/search", s.search)
+	observability(mux, s.archive.ready)
+	return mux
+}
+
+func (s *api) listActs(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	f := actFilter{Date: q.Get("date"), Authority: q.Get("authority")}
+	f.institution, _ = dictionaries.get().canonical(f.Authority)
+	if v := q.Get("year"); v != "" {
+		year, err := strconv.Atoi(v)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("invalid year: %w", err))
+			return
+		}
+		f.Year = year
+	}
+	if f.Date != "" {
+		if _, err := time.Parse(time.DateOnly, f.Date); err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("invalid date: %w", err))
+			return
+		}
+	}
+	journals := s.archive.journals
+	if code := q.Get("journal"); code != "" {
+		j, ok := s.archive.journal(code)
+		if !ok {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("unknown journal %q", code))
+			return
+		}
+		journals = []*journal{j}
+	}
+	limit, offset, err := pagination(q.Get("limit"), q.Get("offset"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	var acts []archivedAct
+	for _, j := range journals {
+		l, err := s.archive.ledger(j)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err)
+			return
+		}
+		for _, e := range l.entries {
+			if e.Status != statusMissing && f.match(e) {
+				acts = append(acts, newArchivedAct(j, e))
+			}
+		}
+	}
+	sortNewestFirst(acts)
+	writeJSON(w, actList{Total: len(acts), Acts: page(acts, limit, offset)})
+}
+
+func (s *api) entry(w http.ResponseWriter, r *http.Request) (*journal, ledgerEntry, bool) {
+	j, ok := s.archive.journal(r.PathValue("journal"))
+	if !ok {
+		writeError(w, http.StatusNotFound, fmt.Errorf("unknown journal %q", r.PathValue("journal")))
+		return nil, ledgerEntry{}, false
+	}
+	year, errYear := strconv.Atoi(r.PathValue("year"))
+	pos, errPos := strconv.Atoi(r.PathValue("pos"))
+	if err := errors.Join(errYear, errPos); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return nil, ledgerEntry{}, false
+	}
+	l, err := s.archive.ledger(j)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return nil, ledgerEntry{}, false
+	}
+	e, ok := l.get(year, pos)
+	if !ok || e.Status == statusMissing {
+		writeError(w, http.StatusNotFound, fmt.Errorf("act %s not found", j.header(year, pos)))
+		return nil, ledgerEntry{}, false
+	}
+	return j, e, true
+}
+
+func (s *api) getAct(w http.ResponseWriter, r *http.Request) {
+	j, e, ok := s.entry(w, r)
+	if !ok {
+		return
+	}
+	writeJSON(w, newArchivedAct(j, e))
+}
+
+func (s *api) getPage(w http.ResponseWriter, r *http.Request) {
+	j, e, ok := s.entry(w, r)
+	if !ok {
+		return
+	}
+	n, err := strconv.Atoi(r.PathValue("page"))
+	if err != nil || n < 1 || n > e.Pages {
+		writeError(w, http.StatusNotFound, fmt.Errorf("page %q not found", r.PathValue("page")))
+		return
+	}
+	pages, err := s.pages(r.Context(), j, e)
+	if err != nil {
+		writeError(w, http.StatusBadGateway, err)
+		return
+	}
+	if n > len(pages) {
+		writeError(w, http.StatusNotFound, fmt.Errorf("page %d not found", n))
+		return
+	}
+	w.Header().Set("Content-Type", "image/jpeg")
+	w.Header().Set("Cache-Control", "public, max-age=86400")
+	w.Write(pages[n-1])
+}
+
+// pages renders page images of the act. Recently rendered acts are kept as their pages are usually requested
+// one by one, and concurrent requests for pages of an act wait for a single render.
+func (s *api) pages(ctx context.Context, j *journal, e ledgerEntry) ([][]byte, error) {
+	key := citation{Journal: j, Year: e.Year, Pos: e.Pos}.eli()
+	s.mu.Lock()
+	if pages, ok := s.rendered.get(key); ok {
+		s.mu.Unlock()
+		return pages, nil
+	}
+	call, ok := s.rendering[key]
+	if !ok {
+		call = &renderCall{done: make(chan struct{})}
+		s.rendering[key] = call
+		go func() {
+			// the render is shared so it is not cancelled with the request that started it
+			call.pages, call.err = s.render(context.WithoutCancel(ctx), j, e.Year, e.Nr, e.Pos)
+			s.mu.Lock()
+			delete(s.rendering, key)
+			if call.err == nil {
+				s.rendered.add(key, call.pages)
+			}
+			s.mu.Unlock()
+			close(call.done)
+		}()
+	}
+	s.mu.Unlock()
+	select {
+	case <-call.done:
+		return call.pages, call.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// pageCache keeps page images of the least recently used acts, the caller synchronizes access.
+type pageCache struct {
+	size int
+	// keys are ELIs of the acts, the least recently used first.
+	keys  []string
+	pages map[string][][]byte
+}
+
+func newPageCache(size int) *pageCache {
+	return &pageCache{size: size, pages: map[string][][]byte{}}
+}
+
+func (c *pageCache) get(key string) ([][]byte, bool) {
+	pages, ok := c.pages[key]
+	if ok {
+		c.use(key)
+	}
+	return pages, ok
+}
+
+func (c *pageCache) add(key string, pages [][]byte) {
+	if _, ok := c.pages[key]; !ok && len(c.keys) == c.size {
+		delete(c.pages, c.keys[0])
+		c.keys = c.keys[1:]
+	}
+	c.pages[key] = pages
+	c.use(key)
+}
+
+func (c *pageCache) use(key string) {
+	c.keys = slices.DeleteFunc(c.keys, func(k string) bool { return k == key })
+	c.keys = append(c.keys, key)
+}
+
+func (s *api) search(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	query := q.Get("q")
+	if strings.TrimSpace(query) == "" {
+		writeError(w, http.StatusBadRequest, errors.New("missing query"))
+		return
+	}
+	limit, offset, err := pagination(q.Get("limit"), q.Get("offset"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	// hits are ranked by relevance, the index is refreshed in the background so it may lag behind the ledgers
+	var acts []archivedAct
+	for _, hit := range s.index.search(query) {
+		c, err := parseELI(hit.ELI)
+		if err != nil {
+			continue
+		}
+		j, ok := s.archive.journal(c.Journal.Code)
+		if !ok {
+			continue
+		}
+		l, err := s.archive.ledger(j)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err)
+			return
+		}
+		if e, ok := l.get(c.Year, c.Pos); ok {
+			acts = append(acts, newArchivedAct(j, e))
+		}
+	}
+	writeJSON(w, actList{Total: len(acts), Acts: page(acts, limit, offset)})
+}
+
+// refreshIndex indexes acts added to the ledgers since the last refresh.
+func (s *api) refreshIndex() error {
+	var ledgers []*ledger
+	for _, j := range s.archive.journals {
+		l, err := s.archive.ledger(j)
+		if err != nil {
+			return err
+		}
+		ledgers = append(ledgers, l)
+	}
+	return s.index.refresh(ledgers)
+}
+
+// refreshIndexEvery refreshes the search index until ctx is cancelled, the first time right away.
+func (s *api) refreshIndexEvery(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := s.refreshIndex(); err != nil {
+			log.WithError(err).Error("Could not refresh search index")
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func sortNewestFirst(acts []archivedAct) {
+	sort.Slice(acts, func(i, j int) bool {
+		if acts[i].Year != acts[j].Year {
+			return acts[i].Year > acts[j].Year
+		}
+		if acts[i].Pos != acts[j].Pos {
+			return acts[i].Pos > acts[j].Pos
+		}
+		return acts[i].Journal < acts[j].Journal
+	})
+}
+
+func pagination(limitParam, offsetParam string) (limit, offset int, err error) {
+	limit = defaultAPILimit
+	if limitParam != "" {
+		if limit, err = strconv.Atoi(limitParam); err != nil || limit < 1 {
+			return 0, 0, fmt.Errorf("invalid limit %q", limitParam)
+		}
+		limit = min(limit, maxAPILimit)
+	}
+	if offsetParam != "" {
+		if offset, err = strconv.Atoi(offsetParam); err != nil || offset < 0 {
+			return 0, 0, fmt.Errorf("invalid offset %q", offsetParam)
+		}
+	}
+	return limit, offset, nil
+}
+
+func page[T any](items []T, limit, offset int) []T {
+	if offset >= len(items) {
+		return []T{}
+	}
+	return items[offset:min(len(items), offset+limit)]
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.WithError(err).Warn("Could not write response")
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+}
+
+// renderPages downloads the act PDF and renders its pages.
+func renderPages(ctx context.Context, j *journal, year, nr, pos int) ([][]byte, error) {
+	pdf, err := j.site.download(ctx, year, nr, pos)
+	if err != nil {
+		return nil, err
+	}
+	doc, err := fitz.NewFromMemory(pdf)
+	if err != nil {
+		return nil, err
+	}
+	defer doc.Close()
+	return convertPDFToJpgs(doc)
+}
+
+func serveCommand(args []string) {
+	fs := flag.NewFlagSet("serve", flag.ExitOnError)
+	addr := fs.String("addr", "localhost:8080", "address to listen on")
+	refresh := fs.Duration("refresh", time.Minute, "how often to index acts added to the ledgers")
+	fs.Parse(args)
+
+	idx, err := openSearchIndex(indexFile)
+	if err != nil {
+		log.WithError(err).Fatal("Could not open index")
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	a := newAPI(newArchive(journals), idx)
+	go a.refreshIndexEvery(ctx, *refresh)
+	server := &http.Server{
+		Addr:              *addr,
+		Handler:           a.handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	go func() {
+		<-ctx.Done()
+		shutdown, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		server.Shutdown(shutdown)
+	}()
+	log.WithField("Addr", *addr).Info("Serving API")
+	if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		log.WithError(err).Fatal("Could not serve API")
+	}
+}
@@ -0,0 +1,236 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func newTestAPI(t *testing.T) (*httptest.Server, *ledger) {
+	t.Helper()
+	dir := t.TempDir()
+	du, mp := *dziennikUstaw, *monitorPolski
+	du.Ledger, mp.Ledger = filepath.Join(dir, "ledger.jsonl"), filepath.Join(dir, "ledger-mp.jsonl")
+	du.Texts, mp.Texts = filepath.Join(dir, "text"), filepath.Join(dir, "text", "mp")
+
+	l, err := du.openLedger()
+	if err != nil {
+		t.Fatal(err)
+	}
+	announced := &date{time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC)}
+	for _, e := range []ledgerEntry{
+		{Year: 2020, Pos: 1, Title: "Rozporządzenie Ministra Finansów w sprawie podatku od nieruchomości", Status: statusPublished,
+			Act:     &Act{Title: "Rozporządzenie", Authority: "Ministra Finansów", Institutions: []string{"Ministra Finansów"}, Announced: announced},
+			Summary: "Stawki podatku", Pages: 2, Posts: map[string]targetPost{"twitter": {ID: "123", SummaryID: "124", Status: statusPublished}}},
+		{Year: 2020, Pos: 2, Title: "Ustawa o pomocy de minimis", Status: statusArchived, Act: &Act{Title: "Ustawa", Authority: "Sejm"}},
+		{Year: 2020, Pos: 3, Status: statusMissing},
+		{Year: 2019, Pos: 7, Title: "Obwieszczenie Ministra Finansów", Status: statusArchived},
+	} {
+		if err := l.record(e); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	idx, err := openSearchIndex(filepath.Join(dir, indexFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := newAPI(newArchive([]*journal{&du, &mp}), idx)
+	a.render = func(_ context.Context, _ *journal, year, nr, pos int) ([][]byte, error) {
+		return [][]byte{[]byte("page 1"), []byte("page 2")}, nil
+	}
+	if err := a.refreshIndex(); err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(a.handler())
+	t.Cleanup(server.Close)
+	return server, l
+}
+
+func getJSON(t *testing.T, url string, status int, v any) {
+	t.Helper()
+	r, err := http.Get(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Body.Close()
+	if r.StatusCode != status {
+		body, _ := io.ReadAll(r.Body)
+		t.Fatalf("GET %s = %s %s, want %d", url, r.Status, body, status)
+	}
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func actELIs(list actList) string {
+	var elis []string
+	for _, a := range list.Acts {
+		elis = append(elis, a.ELI)
+	}
+	return fmt.Sprintf("%d %v", list.Total, elis)
+}
+
+func Test_apiListActs(t *testing.T) {
+	t.Parallel()
+	server, _ := newTestAPI(t)
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{query: "", want: "3 [DU/2020/2 DU/2020/1 DU/2019/7]"},
+		{query: "?year=2020", want: "2 [DU/2020/2 DU/2020/1]"},
+		{query: "?date=2020-01-02", want: "1 [DU/2020/1]"},
+		{query: "?authority=ministra%20finansów", want: "1 [DU/2020/1]"},
+		{query: "?authority=Minister%20Finansów", want: "1 [DU/2020/1]"},
+		{query: "?journal=mp", want: "0 []"},
+		{query: "?limit=1&offset=1", want: "3 [DU/2020/1]"},
+		{query: "?offset=10", want: "3 []"},
+	}
+	for _, tt := range tests {
+		var list actList
+		getJSON(t, server.URL+"/api/acts"+tt.query, http.StatusOK, &list)
+		if got := actELIs(list); got != tt.want {
+			t.Errorf("GET /api/acts%s = %s, want %s", tt.query, got, tt.want)
+		}
+	}
+	for _, query := range []string{"?year=x", "?date=2.01.2020", "?journal=XX", "?limit=0"} {
+		var e map[string]string
+		getJSON(t, server.URL+"/api/acts"+query, http.StatusBadRequest, &e)
+		if e["error"] == "" {
+			t.Errorf("GET /api/acts%s returned no error", query)
+		}
+	}
+}
+
+func Test_apiGetAct(t *testing.T) {
+	t.Parallel()
+	server, l := newTestAPI(t)
+	var act archivedAct
+	getJSON(t, server.URL+"/api/acts/DU/2020/1", http.StatusOK, &act)
+	if act.Title != "Rozporządzenie Ministra Finansów w sprawie podatku od nieruchomości" || act.Summary != "Stawki podatku" ||
+		act.Meta == nil || act.Meta.Authority != "Ministra Finansów" || act.Posts["twitter"].ID != "123" ||
+		act.PDFURL != "https://dziennikustaw.gov.pl/D2020000000101.pdf" || fmt.Sprint(act.Pages) != "[/api/acts/DU/2020/1/pages/1 /api/acts/DU/2020/1/pages/2]" {
+		t.Errorf("act = %+v", act)
+	}
+
+	var e map[string]string
+	getJSON(t, server.URL+"/api/acts/DU/2020/3", http.StatusNotFound, &e)
+	getJSON(t, server.URL+"/api/acts/XX/2020/1", http.StatusNotFound, &e)
+	getJSON(t, server.URL+"/api/acts/DU/2020/x", http.StatusBadRequest, &e)
+
+	// acts archived after the server started are served
+	if err := l.record(ledgerEntry{Year: 2020, Pos: 4, Title: "Nowa ustawa", Status: statusArchived}); err != nil {
+		t.Fatal(err)
+	}
+	getJSON(t, server.URL+"/api/acts/DU/2020/4", http.StatusOK, &act)
+	if act.Title != "Nowa ustawa" {
+		t.Errorf("act = %+v", act)
+	}
+}
+
+func Test_apiGetPage(t *testing.T) {
+	t.Parallel()
+	server, _ := newTestAPI(t)
+	r, err := http.Get(server.URL + "/api/acts/DU/2020/1/pages/2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(r.Body)
+	r.Body.Close()
+	if r.StatusCode != http.StatusOK || r.Header.Get("Content-Type") != "image/jpeg" || string(body) != "page 2" {
+		t.Errorf("GET page = %s %q %q", r.Status, r.Header.Get("Content-Type"), body)
+	}
+	for _, path := range []string{"/api/acts/DU/2020/1/pages/3", "/api/acts/DU/2020/2/pages/1"} {
+		r, err := http.Get(server.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r.Body.Close()
+		if r.StatusCode != http.StatusNotFound {
+			t.Errorf("GET %s = %s, want 404", path, r.Status)
+		}
+	}
+}
+
+func Test_apiSearch(t *testing.T) {
+	t.Parallel()
+	server, _ := newTestAPI(t)
+	var list actList
+	getJSON(t, server.URL+"/api/search?q=de+minimis", http.StatusOK, &list)
+	if got := actELIs(list); got != "1 [DU/2020/2]" {
+		t.Errorf("search = %s", got)
+	}
+	// the act with a shorter title ranks higher
+	getJSON(t, server.URL+"/api/search?q=ministrowi+finansow", http.StatusOK, &list)
+	if got := actELIs(list); got != "2 [DU/2019/7 DU/2020/1]" {
+		t.Errorf("search = %s", got)
+	}
+	var e map[string]string
+	getJSON(t, server.URL+"/api/search", http.StatusBadRequest, &e)
+}
+
+func Test_apiPages(t *testing.T) {
+	t.Parallel()
+	var renders atomic.Int32
+	release := make(chan struct{})
+	a := newAPI(newArchive(journals), nil)
+	a.render = func(_ context.Context, _ *journal, year, nr, pos int) ([][]byte, error) {
+		renders.Add(1)
+		<-release
+		return [][]byte{[]byte(fmt.Sprintf("%d/%d", year, pos))}, nil
+	}
+	acts := []ledgerEntry{{Year: 2020, Pos: 1, Pages: 1}, {Year: 2020, Pos: 2, Pages: 1}}
+	var wg sync.WaitGroup
+	for i := range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			e := acts[i%2]
+			pages, err := a.pages(context.Background(), dziennikUstaw, e)
+			if err != nil || string(pages[0]) != fmt.Sprintf("%d/%d", e.Year, e.Pos) {
+				t.Errorf("pages() = %q, %v", pages, err)
+			}
+		}()
+	}
+	// both acts are rendered at the same time, not one after another
+	for renders.Load() < 2 {
+		time.Sleep(time.Millisecond)
+	}
+	close(release)
+	wg.Wait()
+	// alternating between the acts does not render them again
+	for _, e := range acts {
+		if _, err := a.pages(context.Background(), dziennikUstaw, e); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if renders.Load() != 2 {
+		t.Errorf("renders = %d, want 2", renders.Load())
+	}
+}
+
+func Test_pageCache(t *testing.T) {
+	t.Parallel()
+	c := newPageCache(2)
+	c.add("a", nil)
+	c.add("b", nil)
+	c.get("a")
+	c.add("c", nil)
+	if _, ok := c.get("b"); ok {
+		t.Error("the least recently used act was kept")
+	}
+	for _, key := range []string{"a", "c"} {
+		if _, ok := c.get(key); !ok {
+			t.Errorf("%s was evicted", key)
+		}
+	}
+}
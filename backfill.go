@@ -0,0 +1,143 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+type backfillOptions struct {
+	Year      int
+	From      int
+	To        int
+	Delay     time.Duration
+	Summarize bool
+	Post      bool
+}
+
+func backfillCommand(args []string) {
+	opts := backfillOptions{}
+	fs := flag.NewFlagSet("backfill", flag.ExitOnError)
+	code := fs.String("journal", dziennikUstaw.Code, "journal code, DU or MP")
+	fs.IntVar(&opts.Year, "year", time.Now().Year(), "year of the acts")
+	fs.IntVar(&opts.From, "from", 1, "first position")
+	fs.IntVar(&opts.To, "to", 0, "last position (required)")
+	fs.DurationVar(&opts.Delay, "delay", 5*time.Second, "delay between acts to be polite to the journal website")
+	fs.BoolVar(&opts.Summarize, "summarize", true, "generate AI summaries")
+	fs.BoolVar(&opts.Post, "post", false, "publish acts to configured targets, not only archive them")
+	fs.Parse(args)
+	j, err := journalByCode(*code)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		fs.Usage()
+		os.Exit(2)
+	}
+	if opts.To < opts.From {
+		fmt.Fprintln(os.Stderr, "-to must be greater or equal to -from")
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	l, err := j.openLedger()
+	if err != nil {
+		log.WithError(err).Fatal("Could not open ledger")
+	}
+	var publishers []publisher
+	if opts.Post {
+		publishers, err = newPublishers(newTwitterClients())
+		if err != nil {
+			log.WithError(err).Fatal("Could not configure publishers")
+		}
+	}
+	if err := backfill(ctx, l, opts, j.fetchAct, publishers); err != nil {
+		log.WithError(err).Fatal("Backfill interrupted, run the same command again to resume")
+	}
+	if opts.Post {
+		if err := notifySubscribers(ctx, l); err != nil {
+			log.WithError(err).Error("Could not notify subscribers")
+		}
+	}
+	if err := writeFeeds(l, j.Feed); err != nil {
+		log.WithError(err).Error("Could not write feeds")
+	}
+}
+
+// backfill archives a range of acts, every act is recorded in the ledger as
+// soon as it is processed so an interrupted backfill resumes where it stopped.
+func backfill(ctx context.Context, l *ledger, opts backfillOptions, fetch func(ctx context.Context, year, pos int) (newAct, bool, error), publishers []publisher) error {
+	for pos := opts.From; pos <= opts.To; pos++ {
+		logger := log.WithField("Year", opts.Year).WithField("Pos", pos)
+		if e, ok := l.get(opts.Year, pos); ok && backfilled(e, opts) {
+			logger.Debug("Already backfilled")
+			continue
+		}
+
+		act, found, err := fetch(ctx, opts.Year, pos)
+		if err != nil {
+			return fmt.Errorf("could not fetch %s: %w", l.journal.header(opts.Year, pos), err)
+		}
+		if !found {
+			logger.Info("No data")
+		} else if err := archiveAct(ctx, l, act, opts, publishers); err != nil {
+			return err
+		}
+
+		if pos == opts.To {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(opts.Delay):
+		}
+	}
+	return nil
+}
+
+func backfilled(e ledgerEntry, opts backfillOptions) bool {
+	if opts.Summarize && e.Summary == "" {
+		return false
+	}
+	if opts.Post {
+		return e.Status == statusPublished || e.Status == statusFailed
+	}
+	return true
+}
+
+func archiveAct(ctx context.Context, l *ledger, act newAct, opts backfillOptions, publishers []publisher) error {
+	entry, ok := l.get(act.Year, act.Pos)
+	if !ok {
+		entry = ledgerEntry{Status: statusArchived}
+	}
+	entry.update(act)
+	if opts.Summarize && entry.Summary == "" {
+		summary, err := act.Summary()
+		if err != nil {
+			log.WithError(err).WithField("Year", act.Year).WithField("Pos", act.Pos).Error("Could not get summary")
+		} else {
+			entry.Summary = summary
+		}
+	}
+	if err := l.record(entry); err != nil {
+		return fmt.Errorf("could not save archived act: %w", err)
+	}
+	log.WithField("Year", act.Year).WithField("Pos", act.Pos).WithField("Title", act.Title).Info("Archived")
+
+	if opts.Post {
+		if entry.Summary != "" {
+			summary := entry.Summary
+			act.Summary = func() (string, error) { return summary, nil }
+		}
+		return publishAct(ctx, l, act, publishers)
+	}
+	return nil
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+type fakeJournal struct {
+	missing map[int]bool
+	fail    map[int]bool
+	fetched []int
+}
+
+func (f *fakeJournal) fetch(_ context.Context, year, pos int) (newAct, bool, error) {
+	f.fetched = append(f.fetched, pos)
+	if f.fail[pos] {
+		return newAct{}, true, errors.New("connection reset")
+	}
+	if f.missing[pos] {
+		return newAct{}, false, nil
+	}
+	return newAct{
+		Year:    year,
+		Nr:      78,
+		Pos:     pos,
+		Title:   "Ustawa",
+		Summary: func() (string, error) { return "Podsumowanie", nil },
+	}, true, nil
+}
+
+func Test_backfill(t *testing.T) {
+	t.Parallel()
+	l, err := openLedger(filepath.Join(t.TempDir(), ledgerFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts := backfillOptions{Year: 1997, From: 481, To: 485, Summarize: true}
+	f := &fakeJournal{missing: map[int]bool{482: true}, fail: map[int]bool{484: true}}
+
+	if err := backfill(context.Background(), l, opts, f.fetch, nil); err == nil {
+		t.Fatalf("expected error")
+	}
+	delete(f.fail, 484)
+	f.fetched = nil
+	if err := backfill(context.Background(), l, opts, f.fetch, nil); err != nil {
+		t.Fatal(err)
+	}
+	// 481 and 483 were archived in the first run
+	if len(f.fetched) != 3 || f.fetched[0] != 482 {
+		t.Errorf("fetched after resume = %v", f.fetched)
+	}
+	e, ok := l.get(1997, 483)
+	if !ok || e.Status != statusArchived || e.Nr != 78 || e.Summary != "Podsumowanie" || e.Title != "Ustawa" {
+		t.Errorf("entry = %+v", e)
+	}
+	if _, ok := l.get(1997, 482); ok {
+		t.Errorf("missing act should not be archived")
+	}
+	if y, _ := l.latest(); y != 0 {
+		t.Errorf("archived acts should not move the publishing cursor")
+	}
+}
+
+func Test_backfillPost(t *testing.T) {
+	t.Parallel()
+	l, err := openLedger(filepath.Join(t.TempDir(), ledgerFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := &fakePublisher{name: "fake"}
+	opts := backfillOptions{Year: 2020, From: 1, To: 2, Summarize: true, Post: true}
+	f := &fakeJournal{}
+	if err := backfill(context.Background(), l, opts, f.fetch, []publisher{p}); err != nil {
+		t.Fatal(err)
+	}
+	if e, _ := l.get(2020, 2); e.Status != statusPublished || e.Posts["fake"].SummaryID == "" {
+		t.Errorf("entry = %+v", e)
+	}
+	if len(p.announced) != 2 {
+		t.Errorf("announced = %v", p.announced)
+	}
+}
+
+func Test_backfillCancel(t *testing.T) {
+	t.Parallel()
+	l, err := openLedger(filepath.Join(t.TempDir(), ledgerFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	opts := backfillOptions{Year: 2020, From: 1, To: 10, Delay: time.Hour}
+	f := &fakeJournal{}
+	if err := backfill(ctx, l, opts, f.fetch, nil); !errors.Is(err, context.Canceled) {
+		t.Errorf("backfill() error = %v", err)
+	}
+	if len(f.fetched) != 1 {
+		t.Errorf("fetched = %v", f.fetched)
+	}
+}
@@ -0,0 +1,476 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"io"
+	"net/http"
+	neturl "net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/avast/retry-go"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	blueskyMaxPostLength = 300
+	blueskyMaxAltLength  = 2000
+	// blueskyMaxBlobSize is the largest image accepted in app.bsky.embed.images.
+	blueskyMaxBlobSize = 1000000
+)
+
+type bluesky struct {
+	pds        string
+	identifier string
+	password   string
+	// handles maps institution names to Bluesky handles, the dictionary is used when nil.
+	handles map[string]string
+	client  *http.Client
+
+	did       string
+	accessJwt string
+	dids      map[string]string
+	cids      map[string]string
+	rateLimit rateLimit
+}
+
+// newBlueskyFromEnv returns a Bluesky client configured with BLUESKY_HANDLE and
+// BLUESKY_PASSWORD (an app password) or nil when Bluesky is not configured.
+// BLUESKY_PDS overrides the default PDS and BLUESKY_HANDLES points to a JSON
+// file with institution to handle mapping used instead of the dictionary.
+func newBlueskyFromEnv() (*bluesky, error) {
+	identifier, password := os.Getenv("BLUESKY_HANDLE"), os.Getenv("BLUESKY_PASSWORD")
+	if identifier == "" || password == "" {
+		return nil, nil
+	}
+	pds := os.Getenv("BLUESKY_PDS")
+	if pds == "" {
+		pds = "https://bsky.social"
+	}
+	var handles map[string]string
+	if path := os.Getenv("BLUESKY_HANDLES"); path != "" {
+		handles = map[string]string{}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("could not read Bluesky handles: %w", err)
+		}
+		if err := json.Unmarshal(data, &handles); err != nil {
+			return nil, fmt.Errorf("could not parse Bluesky handles: %w", err)
+		}
+	}
+	return newBluesky(pds, identifier, password, handles), nil
+}
+
+func newBluesky(pds, identifier, password string, handles map[string]string) *bluesky {
+	return &bluesky{
+		pds:        strings.TrimSuffix(pds, "/"),
+		identifier: identifier,
+		password:   password,
+		handles:    handles,
+		client:     &http.Client{Timeout: time.Minute, Transport: tracedTransport(nil)},
+		dids:       map[string]string{},
+		cids:       map[string]string{},
+	}
+}
+
+type blueskyRef struct {
+	URI string `json:"uri"`
+	CID string `json:"cid"`
+}
+
+type blueskyFacet struct {
+	Index    blueskyByteSlice `json:"index"`
+	Features []map[string]any `json:"features"`
+}
+
+type blueskyByteSlice struct {
+	ByteStart int `json:"byteStart"`
+	ByteEnd   int `json:"byteEnd"`
+}
+
+type blueskyPost struct {
+	Type      string         `json:"$type"`
+	Text      string         `json:"text"`
+	CreatedAt string         `json:"createdAt"`
+	Langs     []string       `json:"langs"`
+	Facets    []blueskyFacet `json:"facets,omitempty"`
+	Embed     map[string]any `json:"embed,omitempty"`
+	Reply     map[string]any `json:"reply,omitempty"`
+}
+
+const blueskyTarget = "bluesky"
+
+func (b *bluesky) Name() string {
+	return blueskyTarget
+}
+
+func (b *bluesky) RateLimit() rateLimit {
+	return b.rateLimit
+}
+
+// UploadMedia uploads pages as blobs, returned IDs are JSON blob references to embed in the post.
+func (b *bluesky) UploadMedia(ctx context.Context, act newAct) ([]string, error) {
+	if err := b.login(ctx); err != nil {
+		return nil, fmt.Errorf("could not create session: %w", err)
+	}
+	blobs := make([]string, 0, len(act.Pages))
+	for i, page := range act.Pages {
+		page, err := fitImage(page, blueskyMaxBlobSize)
+		if err != nil {
+			return nil, fmt.Errorf("could not shrink page %d: %w", i+1, err)
+		}
+		var blob struct {
+			Blob json.RawMessage `json:"blob"`
+		}
+		if err := b.xrpc(ctx, "com.atproto.repo.uploadBlob", "image/jpeg", page, &blob); err != nil {
+			return nil, err
+		}
+		blobs = append(blobs, string(blob.Blob))
+	}
+	return blobs, nil
+}
+
+// fitImage re-encodes a JPEG image with a lower quality, and then downscales it, until it has at most size bytes.
+func fitImage(data []byte, size int) ([]byte, error) {
+	if len(data) <= size {
+		return data, nil
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	for {
+		for _, quality := range []int{60, 40} {
+			var buf bytes.Buffer
+			if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: quality}); err != nil {
+				return nil, err
+			}
+			if buf.Len() <= size {
+				return buf.Bytes(), nil
+			}
+		}
+		if img.Bounds().Dx() < 64 || img.Bounds().Dy() < 64 {
+			return nil, fmt.Errorf("image does not fit in %d bytes", size)
+		}
+		img = halve(img)
+	}
+}
+
+// halve downscales the image to half of its width and height averaging every 2x2 block of pixels.
+func halve(img image.Image) image.Image {
+	b := img.Bounds()
+	dst := image.NewRGBA(image.Rect(0, 0, b.Dx()/2, b.Dy()/2))
+	for y := range dst.Bounds().Dy() {
+		for x := range dst.Bounds().Dx() {
+			var sum [4]uint32
+			for _, p := range [4]image.Point{{0, 0}, {1, 0}, {0, 1}, {1, 1}} {
+				r, g, bl, a := img.At(b.Min.X+2*x+p.X, b.Min.Y+2*y+p.Y).RGBA()
+				sum[0], sum[1], sum[2], sum[3] = sum[0]+r, sum[1]+g, sum[2]+bl, sum[3]+a
+			}
+			dst.SetRGBA(x, y, color.RGBA{uint8(sum[0] / 4 >> 8), uint8(sum[1] / 4 >> 8), uint8(sum[2] / 4 >> 8), uint8(sum[3] / 4 >> 8)})
+		}
+	}
+	return dst
+}
+
+func (b *bluesky) Announce(ctx context.Context, act newAct, mediaIDs []string) (string, error) {
+	if err := b.login(ctx); err != nil {
+		return "", fmt.Errorf("could not create session: %w", err)
+	}
+
+	handles := b.handles
+	if handles == nil {
+		handles = dictionaries.get().blueskyHandles(act.journal(), actDate(act.Title))
+	}
+	text, mentions := prepareBlueskyPost(act.journal(), act.Year, act.Nr, act.Pos, act.Title, handles)
+	post := b.newPost(text)
+	for _, m := range mentions {
+		did, err := b.resolveHandle(ctx, m.handle)
+		if err != nil {
+			log.WithError(err).WithField("Handle", m.handle).Warn("Could not resolve Bluesky handle")
+			continue
+		}
+		post.Facets = append(post.Facets, blueskyFacet{
+			Index:    blueskyByteSlice{ByteStart: m.start, ByteEnd: m.end},
+			Features: []map[string]any{{"$type": "app.bsky.richtext.facet#mention", "did": did}},
+		})
+	}
+	pdf := act.journal().pdfUrl(act.Year, act.Nr, act.Pos)
+	start := strings.LastIndex(text, pdf)
+	post.Facets = append(post.Facets, blueskyFacet{
+		Index:    blueskyByteSlice{ByteStart: start, ByteEnd: start + len(pdf)},
+		Features: []map[string]any{{"$type": "app.bsky.richtext.facet#link", "uri": pdf}},
+	})
+
+	header := act.journal().header(act.Year, act.Pos)
+	var images []map[string]any
+	for i, blob := range mediaIDs {
+		alt := fmt.Sprintf("%s, strona %d z %d: %s", header, i+1, len(mediaIDs), act.Title)
+		images = append(images, map[string]any{
+			"alt":   truncateRunes(alt, blueskyMaxAltLength),
+			"image": json.RawMessage(blob),
+		})
+	}
+	if len(images) > 0 {
+		post.Embed = map[string]any{"$type": "app.bsky.embed.images", "images": images}
+	}
+
+	ref, err := b.createPost(ctx, post)
+	if err != nil {
+		return "", err
+	}
+	log.WithField("URI", ref.URI).Info("Published on Bluesky")
+	return ref.URI, nil
+}
+
+// Reply posts text as a reply, parentID is the AT URI of the announcement which is also the thread root.
+func (b *bluesky) Reply(ctx context.Context, parentID, text string) (string, error) {
+	if err := b.login(ctx); err != nil {
+		return "", fmt.Errorf("could not create session: %w", err)
+	}
+	parent, err := b.getRef(ctx, parentID)
+	if err != nil {
+		return "", fmt.Errorf("could not get parent post: %w", err)
+	}
+	reply := b.newPost(text)
+	reply.Reply = map[string]any{"root": parent, "parent": parent}
+	ref, err := b.createPost(ctx, reply)
+	if err != nil {
+		return "", err
+	}
+	log.WithField("URI", ref.URI).Info("Published on Bluesky")
+	return ref.URI, nil
+}
+
+type blueskyMention struct {
+	handle     string
+	start, end int
+}
+
+// prepareBlueskyPost returns the post text with institutions replaced by
+// handles and byte offsets of the inserted mentions.
+func prepareBlueskyPost(j *journal, year, nr, pos int, title string, handles map[string]string) (string, []blueskyMention) {
+	names := make([]string, 0, len(handles))
+	for name := range handles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var mentions []blueskyMention
+	var b strings.Builder
+	last := 0
+	for _, m := range newNameLinker(names, nil).match(title, nil) {
+		b.WriteString(title[last:m.Start])
+		handle := handles[names[m.Name]]
+		mentions = append(mentions, blueskyMention{handle: handle, start: b.Len(), end: b.Len() + 1 + len(handle)})
+		b.WriteString("@" + handle)
+		last = m.End
+	}
+	b.WriteString(title[last:])
+	title = b.String()
+
+	header := j.header(year, pos)
+	pdf := j.pdfUrl(year, nr, pos)
+	max := blueskyMaxPostLength - countGraphemes(header) - countGraphemes(pdf) - 2
+	withEmoji := j.addEmoji(title)
+	short := shortenTitle(withEmoji, max, countGraphemes)
+	text := strings.Join([]string{header, short, pdf}, "\n")
+
+	// mentions are moved after the header and the emoji, mentions cut off by shortening are dropped
+	shift := len(withEmoji) - len(title)
+	kept := mentions[:0]
+	for _, m := range mentions {
+		start, end := shift+m.start, shift+m.end
+		if end <= len(short) && short[start:end] == "@"+m.handle {
+			kept = append(kept, blueskyMention{handle: m.handle, start: len(header) + 1 + start, end: len(header) + 1 + end})
+		}
+	}
+	return text, kept
+}
+
+func (b *bluesky) newPost(text string) blueskyPost {
+	return blueskyPost{
+		Type:      "app.bsky.feed.post",
+		Text:      text,
+		CreatedAt: time.Now().UTC().Format(time.RFC3339),
+		Langs:     []string{"pl"},
+	}
+}
+
+// fit cuts the text to the length limit and drops facets that do not point into the cut text.
+func (p *blueskyPost) fit() {
+	starts := graphemeStarts(p.Text)
+	if len(starts) <= blueskyMaxPostLength {
+		return
+	}
+	cut := starts[blueskyMaxPostLength-1]
+	p.Text = p.Text[:cut] + "…"
+	kept := p.Facets[:0]
+	for _, f := range p.Facets {
+		if f.Index.ByteEnd <= cut {
+			kept = append(kept, f)
+		}
+	}
+	p.Facets = kept
+}
+
+// graphemeStarts returns byte offsets of user-perceived characters (graphemes), Bluesky limits posts by them.
+// Emoji sequences are single graphemes and combining marks belong to the preceding character.
+func graphemeStarts(s string) []int {
+	runes := []rune(s)
+	var starts []int
+	offset := 0
+	for i := 0; i < len(runes); {
+		starts = append(starts, offset)
+		n := emojiSequenceLength(runes[i:])
+		if n == 0 {
+			n = 1
+		}
+		if runes[i] == '\r' && i+1 < len(runes) && runes[i+1] == '\n' {
+			n = 2
+		}
+		for i+n < len(runes) && (unicode.In(runes[i+n], unicode.Mn, unicode.Me) || runes[i+n] == variationSelector || runes[i+n] == zeroWidthJoiner) {
+			n++
+		}
+		for _, r := range runes[i : i+n] {
+			offset += utf8.RuneLen(r)
+		}
+		i += n
+	}
+	return starts
+}
+
+func countGraphemes(s string) int {
+	return len(graphemeStarts(s))
+}
+
+func (b *bluesky) login(ctx context.Context) error {
+	if b.accessJwt != "" {
+		return nil
+	}
+	body, err := json.Marshal(map[string]string{"identifier": b.identifier, "password": b.password})
+	if err != nil {
+		return err
+	}
+	var session struct {
+		AccessJwt string `json:"accessJwt"`
+		DID       string `json:"did"`
+	}
+	if err := b.xrpc(ctx, "com.atproto.server.createSession", "application/json", body, &session); err != nil {
+		return err
+	}
+	b.accessJwt, b.did = session.AccessJwt, session.DID
+	return nil
+}
+
+func (b *bluesky) resolveHandle(ctx context.Context, handle string) (string, error) {
+	if strings.HasPrefix(handle, "did:") {
+		return handle, nil
+	}
+	if did, ok := b.dids[handle]; ok {
+		return did, nil
+	}
+	var resolved struct {
+		DID string `json:"did"`
+	}
+	if err := b.xrpc(ctx, "com.atproto.identity.resolveHandle?handle="+neturl.QueryEscape(handle), "", nil, &resolved); err != nil {
+		return "", err
+	}
+	b.dids[handle] = resolved.DID
+	return resolved.DID, nil
+}
+
+func (b *bluesky) createPost(ctx context.Context, post blueskyPost) (blueskyRef, error) {
+	post.fit()
+	body, err := json.Marshal(map[string]any{
+		"repo":       b.did,
+		"collection": "app.bsky.feed.post",
+		"record":     post,
+	})
+	if err != nil {
+		return blueskyRef{}, err
+	}
+	var ref blueskyRef
+	// a failed post may have been created anyway, it is not retried here so a retry in the next
+	// run, limited by the ledger, is the only one
+	if err := b.call(ctx, "com.atproto.repo.createRecord", "application/json", body, &ref); err != nil {
+		return blueskyRef{}, err
+	}
+	b.cids[ref.URI] = ref.CID
+	return ref, nil
+}
+
+// getRef returns a strong reference to the post, the CID is fetched when the post was created in a previous run.
+func (b *bluesky) getRef(ctx context.Context, uri string) (blueskyRef, error) {
+	if cid, ok := b.cids[uri]; ok {
+		return blueskyRef{URI: uri, CID: cid}, nil
+	}
+	// at://did/collection/rkey
+	parts := strings.Split(strings.TrimPrefix(uri, "at://"), "/")
+	if len(parts) != 3 {
+		return blueskyRef{}, fmt.Errorf("invalid AT URI %s", uri)
+	}
+	query := neturl.Values{"repo": {parts[0]}, "collection": {parts[1]}, "rkey": {parts[2]}}
+	var ref blueskyRef
+	if err := b.xrpc(ctx, "com.atproto.repo.getRecord?"+query.Encode(), "", nil, &ref); err != nil {
+		return blueskyRef{}, err
+	}
+	b.cids[ref.URI] = ref.CID
+	return ref, nil
+}
+
+// xrpc calls an XRPC method retrying temporary failures, requests without content type are queries (GET),
+// others are procedures (POST). Only idempotent methods may be retried.
+func (b *bluesky) xrpc(ctx context.Context, method, contentType string, body []byte, out any) error {
+	return retry.Do(func() error {
+		return b.call(ctx, method, contentType, body, out)
+	}, retry.Context(ctx), retry.Attempts(3), retry.LastErrorOnly(true), traceRetries(ctx))
+}
+
+// call calls an XRPC method once, client errors are unrecoverable.
+func (b *bluesky) call(ctx context.Context, method, contentType string, body []byte, out any) error {
+	httpMethod := http.MethodPost
+	if contentType == "" {
+		httpMethod = http.MethodGet
+	}
+	req, err := http.NewRequestWithContext(ctx, httpMethod, b.pds+"/xrpc/"+method, bytes.NewReader(body))
+	if err != nil {
+		return retry.Unrecoverable(err)
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if b.accessJwt != "" {
+		req.Header.Set("Authorization", "Bearer "+b.accessJwt)
+	}
+	r, err := b.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer r.Body.Close()
+	data, err := io.ReadAll(r.Body)
+	if err != nil {
+		return err
+	}
+	if rl := parseRateLimit(r.Header.Get("RateLimit-Limit"), r.Header.Get("RateLimit-Remaining"), r.Header.Get("RateLimit-Reset")); rl.Limit > 0 {
+		b.rateLimit = rl
+	}
+	if r.StatusCode != http.StatusOK {
+		err := fmt.Errorf("unexpected status %s: %s", r.Status, data)
+		if r.StatusCode >= 400 && r.StatusCode < 500 && r.StatusCode != http.StatusTooManyRequests {
+			return retry.Unrecoverable(err)
+		}
+		return err
+	}
+	return json.Unmarshal(data, out)
+}
@@ -0,0 +1,295 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+type fakePDS struct {
+	mu      sync.Mutex
+	blobs   int
+	records []blueskyPost
+	// failures is how many createRecord calls fail with a server error.
+	failures int
+	creates  int
+}
+
+func newFakePDS(t *testing.T) (*fakePDS, *httptest.Server) {
+	f := &fakePDS{}
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /xrpc/com.atproto.server.createSession", func(w http.ResponseWriter, r *http.Request) {
+		var req map[string]string
+		json.NewDecoder(r.Body).Decode(&req)
+		if req["identifier"] != "du.example.com" || req["password"] != "app-password" {
+			http.Error(w, `{"error":"AuthenticationRequired"}`, http.StatusUnauthorized)
+			return
+		}
+		fmt.Fprint(w, `{"accessJwt":"jwt","did":"did:plc:du"}`)
+	})
+	authorized := func(h http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("Authorization") != "Bearer jwt" {
+				http.Error(w, `{"error":"AuthenticationRequired"}`, http.StatusUnauthorized)
+				return
+			}
+			h(w, r)
+		}
+	}
+	mux.HandleFunc("GET /xrpc/com.atproto.identity.resolveHandle", authorized(func(w http.ResponseWriter, r *http.Request) {
+		handle := r.URL.Query().Get("handle")
+		if handle == "unknown.example.com" {
+			http.Error(w, `{"error":"InvalidRequest"}`, http.StatusBadRequest)
+			return
+		}
+		fmt.Fprintf(w, `{"did":"did:plc:%s"}`, strings.Split(handle, ".")[0])
+	}))
+	mux.HandleFunc("POST /xrpc/com.atproto.repo.uploadBlob", authorized(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		f.mu.Lock()
+		f.blobs++
+		n := f.blobs
+		f.mu.Unlock()
+		fmt.Fprintf(w, `{"blob":{"$type":"blob","ref":{"$link":"bafy%d"},"mimeType":%q,"size":%d}}`, n, r.Header.Get("Content-Type"), len(data))
+	}))
+	mux.HandleFunc("POST /xrpc/com.atproto.repo.createRecord", authorized(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Repo       string      `json:"repo"`
+			Collection string      `json:"collection"`
+			Record     blueskyPost `json:"record"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Repo != "did:plc:du" || req.Collection != "app.bsky.feed.post" {
+			http.Error(w, `{"error":"InvalidRequest"}`, http.StatusBadRequest)
+			return
+		}
+		f.mu.Lock()
+		f.creates++
+		if f.failures > 0 {
+			f.failures--
+			f.mu.Unlock()
+			http.Error(w, `{"error":"InternalServerError"}`, http.StatusInternalServerError)
+			return
+		}
+		f.records = append(f.records, req.Record)
+		n := len(f.records)
+		f.mu.Unlock()
+		fmt.Fprintf(w, `{"uri":"at://did:plc:du/app.bsky.feed.post/%d","cid":"cid%d"}`, n, n)
+	}))
+	mux.HandleFunc("GET /xrpc/com.atproto.repo.getRecord", authorized(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		fmt.Fprintf(w, `{"uri":"at://%s/%s/%s","cid":"cid-%s","value":{}}`, q.Get("repo"), q.Get("collection"), q.Get("rkey"), q.Get("rkey"))
+	}))
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return f, srv
+}
+
+func Test_blueskyPublish(t *testing.T) {
+	t.Parallel()
+	f, srv := newFakePDS(t)
+	handles := map[string]string{
+		"Ministra Zdrowia":       "mz.example.com",
+		"Ministra Sportu":        "unknown.example.com",
+		"Prezesa Rady Ministrów": "premier.example.com",
+	}
+	b := newBluesky(srv.URL, "du.example.com", "app-password", handles)
+	act := newAct{
+		Year:    2020,
+		Pos:     1,
+		Title:   "Obwieszczenie Ministra Zdrowia i Ministra Sportu z dnia 21 maja 2020 r. w sprawie rozporządzenia Ministra Zdrowia",
+		Pages:   [][]byte{{0xff, 0xd8}},
+		Summary: func() (string, error) { return "Podsumowanie", nil },
+	}
+	l := publishForTest(t, b, act)
+	if post := l.entries[actKey{2020, 1}].Posts[blueskyTarget]; post.Status != statusPublished || post.ID != "at://did:plc:du/app.bsky.feed.post/1" {
+		t.Errorf("post = %+v", post)
+	}
+	if len(f.records) != 2 {
+		t.Fatalf("expected 2 records got %d", len(f.records))
+	}
+	post := f.records[0]
+	wantText := "Dz.U. 2020 poz. 1\n📢Obwieszczenie @mz.example.com i @unknown.example.com z dnia 21 maja 2020 r. w sprawie rozporządzenia @mz.example.com\nhttps://dziennikustaw.gov.pl/D2020000000101.pdf"
+	if post.Text != wantText {
+		t.Errorf("text = %q, want %q", post.Text, wantText)
+	}
+	var got []string
+	for _, facet := range post.Facets {
+		feature := facet.Features[0]
+		value := feature["did"]
+		if value == nil {
+			value = feature["uri"]
+		}
+		got = append(got, fmt.Sprintf("%s=%v", post.Text[facet.Index.ByteStart:facet.Index.ByteEnd], value))
+	}
+	want := []string{
+		"@mz.example.com=did:plc:mz",
+		"@mz.example.com=did:plc:mz",
+		"https://dziennikustaw.gov.pl/D2020000000101.pdf=https://dziennikustaw.gov.pl/D2020000000101.pdf",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("facets =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+	images := post.Embed["images"].([]any)
+	if len(images) != 1 || !strings.HasPrefix(images[0].(map[string]any)["alt"].(string), "Dz.U. 2020 poz. 1, strona 1 z 1") {
+		t.Errorf("embed = %v", post.Embed)
+	}
+
+	reply := f.records[1]
+	if reply.Text != "Podsumowanie" {
+		t.Errorf("reply text = %q", reply.Text)
+	}
+	parent := reply.Reply["parent"].(map[string]any)
+	if parent["uri"] != "at://did:plc:du/app.bsky.feed.post/1" || parent["cid"] != "cid1" {
+		t.Errorf("reply = %v", reply.Reply)
+	}
+}
+
+func Test_blueskyLoginFailure(t *testing.T) {
+	t.Parallel()
+	_, srv := newFakePDS(t)
+	b := newBluesky(srv.URL, "du.example.com", "wrong", nil)
+	act := newAct{Year: 2020, Pos: 1, Title: "Ustawa", Summary: func() (string, error) { return "", nil }}
+	l := publishForTest(t, b, act)
+	if post := l.entries[actKey{2020, 1}].Posts[blueskyTarget]; post.Status != statusPending || post.Error == "" {
+		t.Errorf("post = %+v", post)
+	}
+}
+
+func Test_blueskyCreateNotRetried(t *testing.T) {
+	t.Parallel()
+	f, srv := newFakePDS(t)
+	f.failures = 1
+	b := newBluesky(srv.URL, "du.example.com", "app-password", nil)
+	act := newAct{Year: 2020, Pos: 1, Title: "Ustawa", Summary: func() (string, error) { return "", nil }}
+	l := publishForTest(t, b, act)
+	if post := l.entries[actKey{2020, 1}].Posts[blueskyTarget]; post.Status != statusPending || post.Error == "" {
+		t.Errorf("post = %+v", post)
+	}
+	// the post may have been created, the next run retries it
+	if f.creates != 1 {
+		t.Errorf("createRecord called %d times, want 1", f.creates)
+	}
+}
+
+func Test_prepareBlueskyPost(t *testing.T) {
+	t.Parallel()
+	handles := map[string]string{
+		"Ministra Klimatu":              "klimat.example.com",
+		"Ministra Klimatu i Środowiska": "mkis.example.com",
+	}
+	title := "Rozporządzenie Ministra Klimatu i Środowiska z dnia 1 grudnia 2020 r. " + strings.Repeat("w sprawie szczegółowych wymagań ", 20)
+	text, mentions := prepareBlueskyPost(dziennikUstaw, 2020, 0, 2, title, handles)
+	if n := len([]rune(text)); n > blueskyMaxPostLength {
+		t.Errorf("text length %d exceeds %d", n, blueskyMaxPostLength)
+	}
+	if len(mentions) != 1 || text[mentions[0].start:mentions[0].end] != "@mkis.example.com" {
+		t.Errorf("mentions = %v in %q", mentions, text)
+	}
+}
+
+func Test_blueskyReplyToPreviousRun(t *testing.T) {
+	t.Parallel()
+	f, srv := newFakePDS(t)
+	b := newBluesky(srv.URL, "du.example.com", "app-password", nil)
+	if _, err := b.Reply(context.Background(), "at://did:plc:du/app.bsky.feed.post/3k", "Podsumowanie"); err != nil {
+		t.Fatal(err)
+	}
+	root := f.records[0].Reply["root"].(map[string]any)
+	if root["uri"] != "at://did:plc:du/app.bsky.feed.post/3k" || root["cid"] != "cid-3k" {
+		t.Errorf("reply = %v", f.records[0].Reply)
+	}
+}
+
+func Test_countGraphemes(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		text string
+		want int
+	}{
+		{"", 0},
+		{"Dz.U. 2026 poz. 1", 17},
+		{"zażółć gęślą jaźń", 17},
+		{"📢Obwieszczenie", 14},
+		{"👩🏽‍⚖️", 1},
+		{"🇵🇱🇪🇺", 2},
+		{"1️⃣", 1},
+		{"é", 1},
+		{"a\r\nb", 3},
+	}
+	for _, tt := range tests {
+		if got := countGraphemes(tt.text); got != tt.want {
+			t.Errorf("countGraphemes(%q) = %d, want %d", tt.text, got, tt.want)
+		}
+	}
+}
+
+func Test_blueskyPostFit(t *testing.T) {
+	t.Parallel()
+	// 300 graphemes but many more runes and bytes
+	text := strings.Repeat("👩🏽‍⚖️", blueskyMaxPostLength)
+	post := blueskyPost{Text: text}
+	post.fit()
+	if post.Text != text {
+		t.Errorf("post within the limit was cut to %d graphemes", countGraphemes(post.Text))
+	}
+
+	link := "https://dziennikustaw.gov.pl/D2026000000101.pdf"
+	text = "@mz.example.com " + strings.Repeat("📢", blueskyMaxPostLength) + " " + link
+	post = blueskyPost{Text: text, Facets: []blueskyFacet{
+		{Index: blueskyByteSlice{ByteStart: 0, ByteEnd: 15}},
+		{Index: blueskyByteSlice{ByteStart: len(text) - len(link), ByteEnd: len(text)}},
+	}}
+	post.fit()
+	if countGraphemes(post.Text) != blueskyMaxPostLength || !strings.HasSuffix(post.Text, "📢…") {
+		t.Errorf("fit() = %q", post.Text)
+	}
+	if len(post.Facets) != 1 || post.Text[post.Facets[0].Index.ByteStart:post.Facets[0].Index.ByteEnd] != "@mz.example.com" {
+		t.Errorf("facets = %+v", post.Facets)
+	}
+}
+
+func Test_fitImage(t *testing.T) {
+	t.Parallel()
+	noise := image.NewRGBA(image.Rect(0, 0, 600, 600))
+	r := rand.New(rand.NewPCG(1, 2))
+	for i := range noise.Pix {
+		noise.Pix[i] = uint8(r.IntN(256))
+	}
+	var b bytes.Buffer
+	if err := jpeg.Encode(&b, noise, &jpeg.Options{Quality: jpeg.DefaultQuality}); err != nil {
+		t.Fatal(err)
+	}
+	page := b.Bytes()
+
+	if got, err := fitImage(page, len(page)); err != nil || !bytes.Equal(got, page) {
+		t.Errorf("fitImage() changed an image within the limit, err %v", err)
+	}
+	const size = 100000
+	got, err := fitImage(page, size)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) > size {
+		t.Errorf("fitImage() = %d bytes, want at most %d", len(got), size)
+	}
+	img, err := jpeg.Decode(bytes.NewReader(got))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if img.Bounds().Dx() >= 600 {
+		t.Errorf("fitImage() did not downscale noise, width %d", img.Bounds().Dx())
+	}
+	if _, err := fitImage(page, 100); err == nil {
+		t.Error("fitImage() fitted an image in 100 bytes")
+	}
+}
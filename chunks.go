@@ -0,0 +1,105 @@
+package main
+
+import (
+	"context"
+	_ "embed"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/avast/retry-go"
+	log "github.com/sirupsen/logrus"
+)
+
+// maxCondenseRounds limits how many times chunk summaries are summarized again.
+const maxCondenseRounds = 3
+
+//go:embed chunk_prompt.txt
+var chunkPrompt string
+
+// actBoundaryRegexp matches lines starting a part, chapter, article or paragraph of an act.
+var actBoundaryRegexp = regexp.MustCompile(`(?m)^[ \t]*(?:DZIAŁ [IVXLC]+|Dział [IVXLC]+|ROZDZIAŁ \d+[a-z]*|Rozdział \d+[a-z]*|Art\. \d+[a-z]*\.|§ \d+[a-z]*\.)`)
+
+// chunkSplitters split the text on act units first, then on lines and words.
+var chunkSplitters = []func(string) []string{
+	func(s string) []string { return splitBefore(s, actBoundaryRegexp) },
+	func(s string) []string { return strings.SplitAfter(s, "\n") },
+	func(s string) []string { return strings.SplitAfter(s, " ") },
+}
+
+func splitBefore(s string, re *regexp.Regexp) []string {
+	var parts []string
+	start := 0
+	for _, m := range re.FindAllStringIndex(s, -1) {
+		if m[0] > start {
+			parts = append(parts, s[start:m[0]])
+			start = m[0]
+		}
+	}
+	return append(parts, s[start:])
+}
+
+// splitAct splits the act text into chunks of at most budget tokens ending on act unit boundaries when possible.
+func splitAct(text string, budget int, tokens func(string) int) []string {
+	return packChunks(text, budget, tokens, 0)
+}
+
+func packChunks(text string, budget int, tokens func(string) int, level int) []string {
+	var (
+		chunks  []string
+		current strings.Builder
+		used    int
+	)
+	for _, part := range chunkSplitters[level](text) {
+		n := tokens(part)
+		if used+n > budget && current.Len() > 0 {
+			chunks = append(chunks, current.String())
+			current.Reset()
+			used = 0
+		}
+		if n > budget && level+1 < len(chunkSplitters) {
+			chunks = append(chunks, packChunks(part, budget, tokens, level+1)...)
+			continue
+		}
+		current.WriteString(part)
+		used += n
+	}
+	if strings.TrimSpace(current.String()) != "" {
+		chunks = append(chunks, current.String())
+	}
+	return chunks
+}
+
+// condense summarizes chunks of a text exceeding the model input until the summaries fit next to the prompt.
+func condense(ctx context.Context, s summarizer, text string) (string, error) {
+	limit := s.InputTokens() - s.Tokens(prompt)
+	budget := s.InputTokens() - s.Tokens(chunkPrompt)
+	if limit <= 0 || budget <= 0 {
+		return "", retry.Unrecoverable(errors.New("prompt exceeds the model input"))
+	}
+	for round := 1; s.Tokens(text) > limit; round++ {
+		if round > maxCondenseRounds {
+			return "", retry.Unrecoverable(errors.New("text too long"))
+		}
+		chunks := splitAct(text, budget, s.Tokens)
+		log.WithField("Round", round).WithField("Chunks", len(chunks)).Info("Summarizing long act in chunks")
+		summaries := make([]string, 0, len(chunks))
+		for i, chunk := range chunks {
+			var summary string
+			err := retry.Do(func() (err error) {
+				summary, err = s.Complete(ctx, []chatMessage{
+					{Role: "system", Content: chunkPrompt},
+					{Role: "user", Content: chunk},
+				})
+				return err
+			}, retry.Context(ctx), retry.Attempts(3), retry.LastErrorOnly(true), traceRetries(ctx))
+			if err != nil {
+				return "", fmt.Errorf("could not summarize chunk %d of %d: %w", i+1, len(chunks), err)
+			}
+			summaries = append(summaries, summary)
+		}
+		text = strings.Join(summaries, "\n\n")
+	}
+	return text, nil
+}
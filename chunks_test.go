@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func Test_splitAct(t *testing.T) {
+	t.Parallel()
+	article := func(n int) string {
+		return fmt.Sprintf("Art. %d. %s\n", n, strings.Repeat("Minister właściwy do spraw finansów publicznych określi wzór deklaracji. ", 3))
+	}
+	var b strings.Builder
+	b.WriteString("USTAWA\nz dnia 1 grudnia 2025 r.\nustawa budżetowa na rok 2026\n")
+	for chapter := 1; chapter <= 3; chapter++ {
+		fmt.Fprintf(&b, "Rozdział %d\nPrzepisy ogólne\n", chapter)
+		for i := 1; i <= 4; i++ {
+			b.WriteString(article(chapter*10 + i))
+		}
+	}
+	// an article longer than the budget is split on lines
+	b.WriteString("Art. 99. " + strings.Repeat("1) kwota dochodów wynosi 1 000 000 zł;\n", 40))
+	text := b.String()
+
+	budget := 200
+	chunks := splitAct(text, budget, estimateTokens)
+	if strings.Join(chunks, "") != text {
+		t.Fatal("chunks do not add up to the text")
+	}
+	if len(chunks) < 4 {
+		t.Fatalf("expected at least 4 chunks got %d", len(chunks))
+	}
+	for i, chunk := range chunks {
+		if n := estimateTokens(chunk); n > budget {
+			t.Errorf("chunk %d has %d tokens, budget is %d", i, n, budget)
+		}
+		if i > 0 && !strings.HasPrefix(chunk, "Art. ") && !strings.HasPrefix(chunk, "Rozdział ") && !strings.HasPrefix(chunk, "1) ") {
+			t.Errorf("chunk %d does not start on a boundary: %q", i, chunk[:20])
+		}
+	}
+}
+
+func Test_splitBefore(t *testing.T) {
+	t.Parallel()
+	got := splitBefore("Wstęp\nArt. 1. Treść\n§ 1. Ust\nDZIAŁ II\nArt. 2a. Koniec", actBoundaryRegexp)
+	want := []string{"Wstęp\n", "Art. 1. Treść\n", "§ 1. Ust\n", "DZIAŁ II\n", "Art. 2a. Koniec"}
+	if fmt.Sprintf("%q", got) != fmt.Sprintf("%q", want) {
+		t.Errorf("splitBefore() = %q, want %q", got, want)
+	}
+}
+
+type recordingSummarizer struct {
+	fakeSummarizer
+	chunks int
+}
+
+func (r *recordingSummarizer) Complete(ctx context.Context, messages []chatMessage) (string, error) {
+	if messages[0].Content == chunkPrompt {
+		r.chunks++
+	}
+	return r.fakeSummarizer.Complete(ctx, messages)
+}
+
+func Test_summarizeLongAct(t *testing.T) {
+	t.Parallel()
+	s := &recordingSummarizer{fakeSummarizer: fakeSummarizer{inputTokens: 2000}}
+	var b strings.Builder
+	for i := 1; i <= 60; i++ {
+		fmt.Fprintf(&b, "Art. %d. %s\n", i, strings.Repeat("Wydatki budżetu państwa ustala się na kwotę 1 000 zł. ", 4))
+	}
+	summary, err := summarize(context.Background(), s, b.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.chunks < 2 {
+		t.Errorf("expected the act to be summarized in chunks, got %d", s.chunks)
+	}
+	if !strings.HasPrefix(summary, "Streszczenie: Streszczenie: Art. 1.") {
+		t.Errorf("summarize() = %q", summary)
+	}
+
+	short := &recordingSummarizer{fakeSummarizer: fakeSummarizer{inputTokens: 2000}}
+	if _, err := summarize(context.Background(), short, "Art. 1. Ustawa wchodzi w życie."); err != nil || short.chunks != 0 {
+		t.Errorf("short act should not be split, chunks %d, err %v", short.chunks, err)
+	}
+
+	tiny := &recordingSummarizer{fakeSummarizer: fakeSummarizer{inputTokens: 10}}
+	if _, err := summarize(context.Background(), tiny, b.String()); err == nil {
+		t.Errorf("expected error when the prompt does not fit")
+	}
+}
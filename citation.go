@@ -0,0 +1,167 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// citation is a reference to an act published in one of the journals, e.g. "Dz. U. z 2023 r. poz. 1234".
+type citation struct {
+	Journal *journal
+	Year    int
+	// Nr is the journal issue, acts published before 2012 were cited with it.
+	Nr  int
+	Pos int
+	// Consolidated marks citations of a consolidated text (tekst jednolity, "t.j.").
+	Consolidated bool
+	// Amended marks citations of an act together with its amendments ("ze zm.", "z późn. zm.").
+	Amended bool
+}
+
+// eli returns the European Legislation Identifier of the act, e.g. "DU/2023/1234".
+// Positions are numbered within a year so the issue number is not a part of it.
+func (c citation) eli() string {
+	return fmt.Sprintf("%s/%d/%d", c.Journal.Code, c.Year, c.Pos)
+}
+
+// parseELI parses an identifier returned by eli.
+func parseELI(eli string) (citation, error) {
+	parts := strings.Split(eli, "/")
+	if len(parts) != 3 {
+		return citation{}, fmt.Errorf("invalid ELI %q", eli)
+	}
+	j, err := journalByCode(parts[0])
+	if err != nil {
+		return citation{}, err
+	}
+	c := citation{Journal: j}
+	if c.Year, err = strconv.Atoi(parts[1]); err != nil {
+		return citation{}, fmt.Errorf("invalid ELI %q: %w", eli, err)
+	}
+	if c.Pos, err = strconv.Atoi(parts[2]); err != nil {
+		return citation{}, fmt.Errorf("invalid ELI %q: %w", eli, err)
+	}
+	return c, nil
+}
+
+// complete reports whether the citation identifies a single act.
+func (c citation) complete() bool {
+	return c.Year != 0 && c.Pos != 0
+}
+
+func (c citation) String() string {
+	switch {
+	case c.Year == 0:
+		return fmt.Sprintf("%s poz. %d", c.Journal.Prefix, c.Pos)
+	case c.Pos == 0:
+		return fmt.Sprintf("%s %d", c.Journal.Prefix, c.Year)
+	case c.Nr != 0:
+		return fmt.Sprintf("%s %d Nr %d poz. %d", c.Journal.Prefix, c.Year, c.Nr, c.Pos)
+	}
+	return c.Journal.header(c.Year, c.Pos)
+}
+
+var (
+	// citationJournalRegexp matches the journal name which starts every citation, optionally preceded by "t.j.".
+	citationJournalRegexp = regexp.MustCompile(`(?i)(?P<tj>\bt\.\s?j\.\s*:?\s*|\btekst\s+jednolity\s*:?\s*)?(?:(?P<du>\bDz\.\s*U\b\.?|\bDziennik(?:a|u)?\s+Ustaw\b)|(?P<mp>\bM\.\s?P\b\.?|\bMonitor(?:a|ze|em)?\s+Polski(?:ego|m)?\b))`)
+	// citationISAPRegexp matches the short form used by the ISAP database, e.g. "Dz.U.2020.1668" or "Dz.U.1997.78.483".
+	citationISAPRegexp     = regexp.MustCompile(`^\s?(\d{4})\.(?:(\d{1,3})\.)?(\d{1,4})\b`)
+	citationYearRegexp     = regexp.MustCompile(`(?i)^[\s,;]*(?:(?:i|oraz)\s+)?z?\s*(\d{4})\s*(?:r\b\.?)?`)
+	citationNrRegexp       = regexp.MustCompile(`(?i)^[\s,;]*Nr\.?\s*(\d{1,3})\b`)
+	citationPosRegexp      = regexp.MustCompile(`(?i)^[\s,;]*poz\.*\s*(\d{1,4})\b`)
+	citationNextPosRegexp  = regexp.MustCompile(`(?i)^\s*(?:,|i\s|oraz\s)\s*(\d{1,4})\b`)
+	citationAmendedRegexp  = regexp.MustCompile(`(?i)^[\s,]*(?:ze\s*zm\.|z\s*późn\.\s*zm\.|z\s*późniejszymi\s+zmianami)`)
+	citationConsolidRegexp = regexp.MustCompile(`(?i)^\s*t\.\s?j\.`)
+	// citationYearAheadRegexp matches what follows a year, it tells "poz. 1, 2023 r. poz. 2" from "poz. 1, 2023".
+	citationYearAheadRegexp = regexp.MustCompile(`(?i)^\s*(?:r\b|poz\b|nr\b)`)
+)
+
+// parseCitations returns all acts cited in the text in order of appearance. It understands lists of
+// positions ("poz. 1234, 1567 i 1890"), several years in one citation ("z 2022 r. poz. 1 oraz z 2023 r. poz. 2"),
+// issue numbers used before 2012 and the ISAP short form. Citations without a year are returned as well.
+func parseCitations(text string) []citation {
+	var result []citation
+	for offset := 0; offset < len(text); {
+		loc := citationJournalRegexp.FindStringSubmatchIndex(text[offset:])
+		if loc == nil {
+			break
+		}
+		j := dziennikUstaw
+		if loc[6] >= 0 {
+			j = monitorPolski
+		}
+		offset += loc[1]
+		cited, n := parseCitation(j, text[offset:], loc[2] >= 0)
+		result = append(result, cited...)
+		offset += n
+	}
+	return result
+}
+
+// parseCitation parses what follows the journal name, it returns the citations and the number of bytes consumed.
+func parseCitation(j *journal, text string, consolidated bool) ([]citation, int) {
+	offset := 0
+	match := func(r *regexp.Regexp) []string {
+		m := r.FindStringSubmatchIndex(text[offset:])
+		if m == nil {
+			return nil
+		}
+		groups := make([]string, len(m)/2)
+		for i := range groups {
+			if m[2*i] >= 0 {
+				groups[i] = text[offset+m[2*i] : offset+m[2*i+1]]
+			}
+		}
+		offset += m[1]
+		return groups
+	}
+
+	var result []citation
+	if m := match(citationISAPRegexp); m != nil {
+		c := citation{Journal: j, Consolidated: consolidated}
+		c.Year, _ = strconv.Atoi(m[1])
+		c.Nr, _ = strconv.Atoi(m[2])
+		c.Pos, _ = strconv.Atoi(m[3])
+		// ISAP puts "t.j." after the citation
+		if match(citationConsolidRegexp) != nil {
+			c.Consolidated = true
+		}
+		result = append(result, c)
+	} else {
+		year, nr := 0, 0
+		for {
+			start := offset
+			if m := match(citationYearRegexp); m != nil {
+				year, _ = strconv.Atoi(m[1])
+				nr = 0
+			}
+			if m := match(citationNrRegexp); m != nil {
+				nr, _ = strconv.Atoi(m[1])
+			}
+			m := match(citationPosRegexp)
+			if m == nil {
+				offset = start
+				break
+			}
+			for m != nil {
+				c := citation{Journal: j, Year: year, Nr: nr, Consolidated: consolidated}
+				c.Pos, _ = strconv.Atoi(m[1])
+				result = append(result, c)
+				next := offset
+				if m = match(citationNextPosRegexp); m != nil && citationYearAheadRegexp.MatchString(text[offset:]) {
+					// "poz. 1 i 2023 r. poz. 2" continues with another year, not a position
+					offset = next
+					m = nil
+				}
+			}
+		}
+	}
+	if match(citationAmendedRegexp) != nil {
+		for i := range result {
+			result[i].Amended = true
+		}
+	}
+	return result, offset
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func Test_parseCitations(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		text string
+		want string
+	}{
+		{text: "nic", want: ""},
+		{text: "Dz.U. 2020 poz. 1", want: "DU/2020/1"},
+		{text: "Dz.U. 2020 poz. 1, Dz. U. z 2019 r. poz. 2 oraz Dz.U. 1997 nr 78 poz. 483", want: "DU/2020/1 DU/2019/2 DU/1997/483(78)"},
+		{text: "(Dz. U. z 2023 r. poz. 1234, 1567 i 1890)", want: "DU/2023/1234 DU/2023/1567 DU/2023/1890"},
+		{text: "(Dz. U. z 2022 r. poz. 1, 2 i 3 oraz z 2023 r. poz. 4)", want: "DU/2022/1 DU/2022/2 DU/2022/3 DU/2023/4"},
+		{text: "Dz.U. 2022 poz. 1, 2023 poz. 4", want: "DU/2022/1 DU/2023/4"},
+		{text: "(Dz. U. z 2004 r. Nr 19, poz. 177, Nr 96, poz. 959 i 960)", want: "DU/2004/177(19) DU/2004/959(96) DU/2004/960(96)"},
+		{text: "(t.j. Dz. U. z 2023 r. poz. 1234)", want: "DU/2023/1234 t.j."},
+		{text: "tekst jednolity: Dz.U. 2023 poz. 5", want: "DU/2023/5 t.j."},
+		{text: "Dz.U.2020.1668 t.j.", want: "DU/2020/1668 t.j."},
+		{text: "Dz.U.2018.0.1799 t.j.", want: "DU/2018/1799 t.j."},
+		{text: "(Dz. U. z 2023 r. poz. 1234 ze zm.)", want: "DU/2023/1234 zm."},
+		{text: "(Dz.U. z 2019 r. poz. 821, z późn. zm.)", want: "DU/2019/821 zm."},
+		{text: "(t.j. Dz. U. z 2022 r. poz. 1, 2 z późniejszymi zmianami)", want: "DU/2022/1 t.j. zm. DU/2022/2 t.j. zm."},
+		{text: "(M.P. z 2023 r. poz. 12)", want: "MP/2023/12"},
+		{text: "M. P. 2005 Nr 12, poz. 34 i Dz.U. 2020 poz. 1", want: "MP/2005/34(12) DU/2020/1"},
+		{text: "ogłoszone w Monitorze Polskim z 2024 r. poz. 7", want: "MP/2024/7"},
+		{text: "Dziennik Ustaw Dz.U.2019.1347 t.j.", want: "DU/2019/1347 t.j."},
+		{text: "/Dz.U. poz.2157/", want: "DU/0/2157"},
+		{text: "Dz.U. z 2020 r. dotyczy 2000 osób", want: ""},
+	}
+	for _, tt := range tests {
+		var got []string
+		for _, c := range parseCitations(tt.text) {
+			s := c.eli()
+			if c.Nr != 0 {
+				s += fmt.Sprintf("(%d)", c.Nr)
+			}
+			if c.Consolidated {
+				s += " t.j."
+			}
+			if c.Amended {
+				s += " zm."
+			}
+			got = append(got, s)
+		}
+		if strings.Join(got, " ") != tt.want {
+			t.Errorf("parseCitations(%q) = %q, want %q", tt.text, strings.Join(got, " "), tt.want)
+		}
+	}
+}
+
+func Test_citationString(t *testing.T) {
+	t.Parallel()
+	for c, want := range map[citation]string{
+		{Journal: dziennikUstaw, Year: 2023, Pos: 1234}:        "Dz.U. 2023 poz. 1234",
+		{Journal: dziennikUstaw, Year: 1997, Nr: 78, Pos: 483}: "Dz.U. 1997 Nr 78 poz. 483",
+		{Journal: monitorPolski, Year: 2023, Pos: 12}:          "M.P. 2023 poz. 12",
+		{Journal: dziennikUstaw, Pos: 7}:                       "Dz.U. poz. 7",
+		{Journal: dziennikUstaw, Year: 2023}:                   "Dz.U. 2023",
+	} {
+		if got := c.String(); got != want {
+			t.Errorf("%v.String() = %q, want %q", c.eli(), got, want)
+		}
+	}
+}
@@ -0,0 +1,346 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/gen2brain/go-fitz"
+	log "github.com/sirupsen/logrus"
+)
+
+// command is a subcommand of the CLI, run gets the arguments following its name.
+type command struct {
+	name  string
+	usage string
+	run   func(args []string)
+}
+
+var commands = []command{
+	{"post", "check for new acts and publish them, the default command", postCommand},
+	{"preview", "<year> <pos>  show the posts of an act without publishing them", previewCommand},
+	{"backfill", "archive a range of acts", backfillCommand},
+	{"lookup", `"<citation>"  show acts cited in the text, e.g. "Dz.U. z 2023 r. poz. 1234"`, lookupCommand},
+	{"summarize", "<pdf>  summarize an act PDF", summarizeCommand},
+	{"render", "<pdf>  render act PDF pages as they are posted", renderCommand},
+	{"history", "show the amendment history of an act", historyCommand},
+	{"search", "<query>  search archived acts", searchCommand},
+	{"serve", "serve the archive over HTTP", serveCommand},
+	{"daemon", "check for new acts on schedule", daemonCommand},
+	{"dictionaries", "validate the handle and emoji dictionary and show entries used in recent acts", dictionariesCommand},
+	{"subscriptions", "list | add | remove  manage notification rules in subscriptions.json", subscriptionsCommand},
+}
+
+func usage() {
+	w := flag.CommandLine.Output()
+	fmt.Fprintf(w, "Usage: %s [-config file] <command> [flags] [arguments]\n\nCommands:\n", filepath.Base(os.Args[0]))
+	for _, c := range commands {
+		fmt.Fprintf(w, "  %-14s %s\n", c.name, c.usage)
+	}
+	fmt.Fprintf(w, "\nRun %s <command> -h for the flags of the command.\n\nGlobal flags:\n", filepath.Base(os.Args[0]))
+	flag.PrintDefaults()
+}
+
+// runCommand runs the command named by the first argument, post when there is none.
+func runCommand(args []string) {
+	name := "post"
+	if len(args) > 0 {
+		name, args = args[0], args[1:]
+	}
+	if name == "help" {
+		usage()
+		return
+	}
+	for _, c := range commands {
+		if c.name == name {
+			c.run(args)
+			return
+		}
+	}
+	fmt.Fprintf(flag.CommandLine.Output(), "unknown command %q\n\n", name)
+	usage()
+	os.Exit(2)
+}
+
+// exitUsage reports invalid arguments of a command.
+func exitUsage(fs *flag.FlagSet, err error) {
+	fmt.Fprintln(fs.Output(), err)
+	fs.Usage()
+	os.Exit(2)
+}
+
+func postCommand(args []string) {
+	fs := flag.NewFlagSet("post", flag.ExitOnError)
+	dry := fs.Bool("dry", false, "prepare new acts without publishing them, DRY")
+	codes := fs.String("journals", "", "comma separated journal codes to publish, JOURNALS")
+	fs.Parse(args)
+	if *dry {
+		os.Setenv("DRY", "1")
+	}
+	if *codes != "" {
+		os.Setenv("JOURNALS", *codes)
+	}
+	if err := run(context.Background()); err != nil {
+		log.WithError(err).Fatal("Could not publish")
+	}
+}
+
+// positionArgs parses the year and position arguments.
+func positionArgs(args []string) (year, pos int, err error) {
+	if len(args) != 2 {
+		return 0, 0, fmt.Errorf("expected <year> <pos>, got %d arguments", len(args))
+	}
+	year, errYear := strconv.Atoi(args[0])
+	pos, errPos := strconv.Atoi(args[1])
+	if errYear != nil || errPos != nil || year <= 0 || pos <= 0 {
+		return 0, 0, fmt.Errorf("invalid year %q or position %q", args[0], args[1])
+	}
+	return year, pos, nil
+}
+
+func previewCommand(args []string) {
+	fs := flag.NewFlagSet("preview", flag.ExitOnError)
+	code := fs.String("journal", dziennikUstaw.Code, "journal code, DU or MP")
+	summarize := fs.Bool("summarize", true, "generate the AI summary")
+	fs.Parse(args)
+	j, err := journalByCode(*code)
+	if err != nil {
+		exitUsage(fs, err)
+	}
+	year, pos, err := positionArgs(fs.Args())
+	if err != nil {
+		exitUsage(fs, err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	act, found, err := j.fetchAct(ctx, year, pos)
+	if err != nil {
+		log.WithError(err).Fatal("Could not fetch act")
+	}
+	if !found {
+		log.Fatalf("%s is not published", j.header(year, pos))
+	}
+	if err := preview(os.Stdout, act, *summarize); err != nil {
+		log.WithError(err).Fatal("Could not preview act")
+	}
+}
+
+// preview prints the announcement and the summary reply the act would be published with.
+func preview(w io.Writer, act newAct, summarize bool) error {
+	tweet := act.journal().prepareTweet(act.Year, act.Nr, act.Pos, act.Title)
+	fmt.Fprintf(w, "%s\n[%d characters, %d pages]\n", tweet, tweetLength(tweet), len(act.Pages))
+	if !summarize {
+		return nil
+	}
+	summary, err := act.Summary()
+	if err != nil {
+		return fmt.Errorf("could not summarize: %w", err)
+	}
+	reply := withLinks(summary, act.Links)
+	fmt.Fprintf(w, "\n%s\n[%d characters]\n", reply, tweetLength(reply))
+	return nil
+}
+
+func lookupCommand(args []string) {
+	fs := flag.NewFlagSet("lookup", flag.ExitOnError)
+	summarize := fs.Bool("summarize", false, "generate AI summaries of acts that were not published by the bot")
+	fs.Parse(args)
+	if fs.NArg() == 0 {
+		exitUsage(fs, fmt.Errorf("expected a citation, e.g. %q", "Dz.U. z 2023 r. poz. 1234"))
+	}
+
+	ledgers := map[*journal]*ledger{}
+	for _, j := range journals {
+		l, err := j.openLedger()
+		if err != nil {
+			log.WithError(err).Fatal("Could not open ledger")
+		}
+		ledgers[j] = l
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	fetch := func(ctx context.Context, j *journal, year, pos int) (newAct, bool, error) {
+		return j.fetchAct(ctx, year, pos)
+	}
+	if err := lookupCitations(ctx, os.Stdout, strings.Join(fs.Args(), " "), ledgers, fetch, *summarize); err != nil {
+		log.WithError(err).Fatal("Could not look up acts")
+	}
+}
+
+// lookupCitations prints acts cited in the text, acts the bot has not seen are fetched.
+func lookupCitations(ctx context.Context, w io.Writer, text string, ledgers map[*journal]*ledger,
+	fetch func(ctx context.Context, j *journal, year, pos int) (newAct, bool, error), summarize bool) error {
+	citations := parseCitations(text)
+	if len(citations) == 0 {
+		return fmt.Errorf("no citation found in %q", text)
+	}
+	seen := map[string]bool{}
+	for _, c := range citations {
+		if !c.complete() {
+			fmt.Fprintf(w, "%s: year or position missing\n\n", c)
+			continue
+		}
+		if seen[c.eli()] {
+			continue
+		}
+		seen[c.eli()] = true
+
+		entry, ok := ledgers[c.Journal].get(c.Year, c.Pos)
+		if !ok || entry.Title == "" {
+			act, found, err := fetch(ctx, c.Journal, c.Year, c.Pos)
+			if err != nil {
+				return fmt.Errorf("could not fetch %s: %w", c, err)
+			}
+			if !found {
+				fmt.Fprintf(w, "%s: not published\n\n", c)
+				continue
+			}
+			entry.update(act)
+			if summarize && entry.Summary == "" {
+				if entry.Summary, err = act.Summary(); err != nil {
+					return fmt.Errorf("could not summarize %s: %w", c, err)
+				}
+			}
+		}
+		status := string(entry.Status)
+		if status == "" {
+			status = "not archived"
+		}
+		fmt.Fprintf(w, "%s [%s]\n%s\n%s\n", c.Journal.header(entry.Year, entry.Pos), status, entry.Title,
+			c.Journal.pdfUrl(entry.Year, entry.Nr, entry.Pos))
+		if entry.Summary != "" || len(entry.Links) > 0 {
+			fmt.Fprintln(w, strings.TrimSpace(withLinks(entry.Summary, entry.Links)))
+		}
+		fmt.Fprintln(w)
+	}
+	return nil
+}
+
+func summarizeCommand(args []string) {
+	fs := flag.NewFlagSet("summarize", flag.ExitOnError)
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		exitUsage(fs, fmt.Errorf("expected a PDF file"))
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	s, err := llm()
+	if err != nil {
+		log.WithError(err).Fatal("Could not configure summarizer")
+	}
+	if err := summarizePDF(ctx, os.Stdout, s, fs.Arg(0)); err != nil {
+		log.WithError(err).Fatal("Could not summarize")
+	}
+}
+
+// summarizePDF prints the summary of the act PDF.
+func summarizePDF(ctx context.Context, w io.Writer, s summarizer, path string) error {
+	doc, err := fitz.New(path)
+	if err != nil {
+		return err
+	}
+	defer doc.Close()
+	text, err := getPDFText(doc)
+	if err != nil {
+		return fmt.Errorf("could not get pdf text: %w", err)
+	}
+	summary, err := summarize(ctx, s, text)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(w, "%s\n[%d characters]\n", summary, tweetLength(summary))
+	return nil
+}
+
+func renderCommand(args []string) {
+	fs := flag.NewFlagSet("render", flag.ExitOnError)
+	out := fs.String("out", ".", "directory to write page images to")
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		exitUsage(fs, fmt.Errorf("expected a PDF file"))
+	}
+	paths, err := renderPDF(fs.Arg(0), *out)
+	if err != nil {
+		log.WithError(err).Fatal("Could not render")
+	}
+	if len(paths) == 0 {
+		log.Info("No images, acts with more than 4 pages are posted without them")
+	}
+	for _, path := range paths {
+		fmt.Println(path)
+	}
+}
+
+// renderPDF writes the images posted with the act, e.g. D2020000000101-1.jpg, and returns their paths.
+func renderPDF(path, dir string) ([]string, error) {
+	doc, err := fitz.New(path)
+	if err != nil {
+		return nil, err
+	}
+	defer doc.Close()
+	pages, err := convertPDFToJpgs(doc)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	paths := make([]string, 0, len(pages))
+	for i, page := range pages {
+		p := filepath.Join(dir, fmt.Sprintf("%s-%d.jpg", name, i+1))
+		if err := os.WriteFile(p, page, 0o644); err != nil {
+			return nil, err
+		}
+		paths = append(paths, p)
+	}
+	return paths, nil
+}
+
+func dictionariesCommand(args []string) {
+	fs := flag.NewFlagSet("dictionaries", flag.ExitOnError)
+	path := fs.String("file", dictionaries.file(), "dictionary file, DICTIONARIES")
+	last := fs.Int("last", 100, "number of the latest archived acts to check")
+	fs.Parse(args)
+	if *last <= 0 {
+		exitUsage(fs, fmt.Errorf("invalid number of acts %d", *last))
+	}
+	d, err := loadDictionary(*path)
+	if err != nil {
+		log.WithError(err).Fatal("Could not load dictionary")
+	}
+	ledgers := map[*journal]*ledger{}
+	for _, j := range journals {
+		l, err := j.openLedger()
+		if err != nil {
+			log.WithError(err).Fatal("Could not open ledger")
+		}
+		ledgers[j] = l
+	}
+	dictionaryUsage(os.Stdout, d, latestActs(ledgers, *last))
+}
+
+// latestActs returns up to n newest archived acts with a title across journals.
+func latestActs(ledgers map[*journal]*ledger, n int) []archivedAct {
+	var acts []archivedAct
+	for j, l := range ledgers {
+		for _, e := range l.entries {
+			if e.Status != statusMissing && e.Title != "" {
+				acts = append(acts, newArchivedAct(j, e))
+			}
+		}
+	}
+	sortNewestFirst(acts)
+	if len(acts) > n {
+		acts = acts[:n]
+	}
+	return acts
+}
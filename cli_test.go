@@ -0,0 +1,127 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func Test_positionArgs(t *testing.T) {
+	t.Parallel()
+	if year, pos, err := positionArgs([]string{"2024", "15"}); err != nil || year != 2024 || pos != 15 {
+		t.Errorf("positionArgs() = %d, %d, %v", year, pos, err)
+	}
+	for _, args := range [][]string{nil, {"2024"}, {"2024", "x"}, {"2024", "0"}, {"2024", "1", "2"}} {
+		if _, _, err := positionArgs(args); err == nil {
+			t.Errorf("positionArgs(%q) returned no error", args)
+		}
+	}
+}
+
+func Test_preview(t *testing.T) {
+	t.Parallel()
+	act := newAct{
+		Year: 2020, Pos: 1, Title: "Rozporządzenie Ministra Finansów z dnia 27 grudnia 2019 r. w sprawie zwolnień od podatku",
+		Pages:   [][]byte{{}, {}},
+		Summary: func() (string, error) { return "Zwolnienia od podatku.", nil },
+		Links:   []actLink{{Relation: relationAmends, Act: "DU/2019/5"}},
+	}
+	var b strings.Builder
+	if err := preview(&b, act, true); err != nil {
+		t.Fatal(err)
+	}
+	want := `Dz.U. 2020 poz. 1
+Rozporządzenie @MF_gov_PL z dnia 27 grudnia 2019 r. w sprawie zwolnień od podatku
+https://dziennikustaw.gov.pl/D2020000000101.pdf
+[123 characters, 2 pages]
+
+Zwolnienia od podatku.
+Zmienia: Dz.U. 2019 poz. 5
+[49 characters]
+`
+	if got := b.String(); got != want {
+		t.Errorf("preview() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func Test_lookupCitations(t *testing.T) {
+	t.Parallel()
+	l, err := openLedger(filepath.Join(t.TempDir(), ledgerFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := l.record(ledgerEntry{Year: 2020, Pos: 1, Title: "Rozporządzenie w sprawie podatku", Status: statusPublished, Summary: "Podatek"}); err != nil {
+		t.Fatal(err)
+	}
+	var fetched []int
+	fetch := func(_ context.Context, j *journal, year, pos int) (newAct, bool, error) {
+		fetched = append(fetched, pos)
+		if pos == 3 {
+			return newAct{}, false, nil
+		}
+		return newAct{Journal: j, Year: year, Nr: 5, Pos: pos, Title: "Ustawa o drogach",
+			Summary: func() (string, error) { return "Drogi", nil }}, true, nil
+	}
+
+	var b strings.Builder
+	err = lookupCitations(context.Background(), &b, "Dz.U. z 2020 r. poz. 1, 2 i 3 oraz Dz.U. poz. 7", map[*journal]*ledger{dziennikUstaw: l}, fetch, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `Dz.U. 2020 poz. 1 [published]
+Rozporządzenie w sprawie podatku
+https://dziennikustaw.gov.pl/D2020000000101.pdf
+Podatek
+
+Dz.U. 2020 poz. 2 [not archived]
+Ustawa o drogach
+https://dziennikustaw.gov.pl/D2020005000201.pdf
+Drogi
+
+Dz.U. 2020 poz. 3: not published
+
+Dz.U. poz. 7: year or position missing
+
+`
+	if got := b.String(); got != want {
+		t.Errorf("lookupCitations() =\n%s\nwant\n%s", got, want)
+	}
+	if len(fetched) != 2 {
+		t.Errorf("fetched = %v", fetched)
+	}
+	// the lookup does not change the ledger
+	if _, ok := l.get(2020, 2); ok {
+		t.Errorf("looked up act was archived")
+	}
+	if err := lookupCitations(context.Background(), &b, "ustawa o drogach", nil, fetch, false); err == nil {
+		t.Errorf("expected no citation error")
+	}
+}
+
+func Test_summarizePDF(t *testing.T) {
+	t.Parallel()
+	var b strings.Builder
+	if err := summarizePDF(context.Background(), &b, fakeSummarizer{}, "testdata/D2020000000101.pdf"); err != nil {
+		t.Fatal(err)
+	}
+	if got := b.String(); !strings.HasPrefix(got, "Streszczenie: DZIENNIK USTAW") || !strings.HasSuffix(got, " characters]\n") {
+		t.Errorf("summarizePDF() = %q", got)
+	}
+}
+
+func Test_renderPDF(t *testing.T) {
+	t.Parallel()
+	dir := filepath.Join(t.TempDir(), "pages")
+	paths, err := renderPDF("testdata/D2020000000101.pdf", dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(paths) != 2 || paths[1] != filepath.Join(dir, "D2020000000101-2.jpg") {
+		t.Fatalf("renderPDF() = %v", paths)
+	}
+	if page, err := os.ReadFile(paths[0]); err != nil || len(page) < 2 || page[0] != 0xFF || page[1] != 0xD8 {
+		t.Errorf("page is not a JPEG: %v", err)
+	}
+}
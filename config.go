@@ -0,0 +1,195 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// defaultConfigFiles are looked up in the working directory when no config file is given.
+var defaultConfigFiles = []string{"du.yaml", "du.yml", "du.toml"}
+
+// config is the YAML or TOML config file. Every setting is a default for an environment
+// variable so the variables set override the file, and command flags override both.
+type config struct {
+	Journals []string `yaml:"journals" toml:"journals"`
+	Dry      bool     `yaml:"dry" toml:"dry"`
+	// Dictionaries is the file with institution handles and emojis.
+	Dictionaries string `yaml:"dictionaries" toml:"dictionaries"`
+	// Texts is the directory with extracted act texts.
+	Texts      string         `yaml:"texts" toml:"texts"`
+	Limits     limitsConfig   `yaml:"limits" toml:"limits"`
+	Summarizer summarizerFile `yaml:"summarizer" toml:"summarizer"`
+	Targets    targetsConfig  `yaml:"targets" toml:"targets"`
+	SMTP       smtpConfig     `yaml:"smtp" toml:"smtp"`
+	Tracing    tracingConfig  `yaml:"tracing" toml:"tracing"`
+}
+
+type limitsConfig struct {
+	// NewActs is how many acts are published in a single run.
+	NewActs int `yaml:"new_acts" toml:"new_acts"`
+	// DiscoveryWindow is how many consecutive missing positions end the scan.
+	DiscoveryWindow int `yaml:"discovery_window" toml:"discovery_window"`
+}
+
+type summarizerFile struct {
+	Provider    string   `yaml:"provider" toml:"provider"`
+	Model       string   `yaml:"model" toml:"model"`
+	URL         string   `yaml:"url" toml:"url"`
+	APIKey      string   `yaml:"api_key" toml:"api_key"`
+	Temperature *float64 `yaml:"temperature" toml:"temperature"`
+	MaxTokens   int      `yaml:"max_tokens" toml:"max_tokens"`
+	InputTokens int      `yaml:"input_tokens" toml:"input_tokens"`
+}
+
+type targetsConfig struct {
+	Twitter struct {
+		ConsumerKey    string `yaml:"consumer_key" toml:"consumer_key"`
+		ConsumerSecret string `yaml:"consumer_secret" toml:"consumer_secret"`
+		AccessToken    string `yaml:"access_token" toml:"access_token"`
+		AccessSecret   string `yaml:"access_secret" toml:"access_secret"`
+		ReplySearch    string `yaml:"reply_search" toml:"reply_search"`
+	} `yaml:"twitter" toml:"twitter"`
+	Mastodon struct {
+		Server string `yaml:"server" toml:"server"`
+		Token  string `yaml:"token" toml:"token"`
+	} `yaml:"mastodon" toml:"mastodon"`
+	Bluesky struct {
+		Handle   string `yaml:"handle" toml:"handle"`
+		Password string `yaml:"password" toml:"password"`
+		PDS      string `yaml:"pds" toml:"pds"`
+		// Handles is a JSON file with institution to handle mapping.
+		Handles string `yaml:"handles" toml:"handles"`
+	} `yaml:"bluesky" toml:"bluesky"`
+}
+
+type smtpConfig struct {
+	Addr     string `yaml:"addr" toml:"addr"`
+	From     string `yaml:"from" toml:"from"`
+	Username string `yaml:"username" toml:"username"`
+	Password string `yaml:"password" toml:"password"`
+}
+
+type tracingConfig struct {
+	Exporter string `yaml:"exporter" toml:"exporter"`
+	Endpoint string `yaml:"endpoint" toml:"endpoint"`
+	File     string `yaml:"file" toml:"file"`
+}
+
+// parseConfig reads the config, the format is chosen by the file extension.
+func parseConfig(path string) (config, error) {
+	var c config
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return c, err
+	}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		// unknown keys are mistakes, e.g. a misspelled token would silently disable a target
+		d := yaml.NewDecoder(bytes.NewReader(data))
+		d.KnownFields(true)
+		if err := d.Decode(&c); err != nil && !errors.Is(err, io.EOF) {
+			return c, fmt.Errorf("could not parse %s: %w", path, err)
+		}
+	case ".toml":
+		meta, err := toml.Decode(string(data), &c)
+		if err != nil {
+			return c, fmt.Errorf("could not parse %s: %w", path, err)
+		}
+		if undecoded := meta.Undecoded(); len(undecoded) > 0 {
+			return c, fmt.Errorf("could not parse %s: unknown field %q", path, undecoded[0].String())
+		}
+	default:
+		return c, fmt.Errorf("unknown config format %q, expected .yaml, .yml or .toml", filepath.Ext(path))
+	}
+	return c, nil
+}
+
+// env returns the environment variables the config sets.
+func (c config) env() map[string]string {
+	env := map[string]string{
+		"JOURNALS":                    strings.Join(c.Journals, ","),
+		"DICTIONARIES":                c.Dictionaries,
+		"TEXTS":                       c.Texts,
+		"DISCOVERY_WINDOW":            optionalInt(c.Limits.DiscoveryWindow),
+		"MAX_NEW_ACTS":                optionalInt(c.Limits.NewActs),
+		"SUMMARIZER":                  c.Summarizer.Provider,
+		"SUMMARIZER_MODEL":            c.Summarizer.Model,
+		"SUMMARIZER_URL":              c.Summarizer.URL,
+		"SUMMARIZER_API_KEY":          c.Summarizer.APIKey,
+		"SUMMARIZER_MAX_TOKENS":       optionalInt(c.Summarizer.MaxTokens),
+		"SUMMARIZER_INPUT_TOKENS":     optionalInt(c.Summarizer.InputTokens),
+		"consumerKey":                 c.Targets.Twitter.ConsumerKey,
+		"consumerSecret":              c.Targets.Twitter.ConsumerSecret,
+		"accessToken":                 c.Targets.Twitter.AccessToken,
+		"accessSecret":                c.Targets.Twitter.AccessSecret,
+		"REPLY_SEARCH":                c.Targets.Twitter.ReplySearch,
+		"MASTODON_SERVER":             c.Targets.Mastodon.Server,
+		"MASTODON_TOKEN":              c.Targets.Mastodon.Token,
+		"BLUESKY_HANDLE":              c.Targets.Bluesky.Handle,
+		"BLUESKY_PASSWORD":            c.Targets.Bluesky.Password,
+		"BLUESKY_PDS":                 c.Targets.Bluesky.PDS,
+		"BLUESKY_HANDLES":             c.Targets.Bluesky.Handles,
+		"SMTP_ADDR":                   c.SMTP.Addr,
+		"SMTP_FROM":                   c.SMTP.From,
+		"SMTP_USERNAME":               c.SMTP.Username,
+		"SMTP_PASSWORD":               c.SMTP.Password,
+		"OTEL_TRACES_EXPORTER":        c.Tracing.Exporter,
+		"OTEL_EXPORTER_OTLP_ENDPOINT": c.Tracing.Endpoint,
+		"OTEL_TRACES_FILE":            c.Tracing.File,
+	}
+	if c.Summarizer.Temperature != nil {
+		env["SUMMARIZER_TEMPERATURE"] = strconv.FormatFloat(*c.Summarizer.Temperature, 'f', -1, 64)
+	}
+	if c.Dry {
+		env["DRY"] = "1"
+	}
+	for name, value := range env {
+		if value == "" {
+			delete(env, name)
+		}
+	}
+	return env
+}
+
+// optionalInt formats n, zero means unset.
+func optionalInt(n int) string {
+	if n == 0 {
+		return ""
+	}
+	return strconv.Itoa(n)
+}
+
+// loadConfig applies the config file to the environment, variables already set are kept.
+// Without a path the default files are tried and a missing one is not an error.
+func loadConfig(path string) (string, error) {
+	if path == "" {
+		for _, name := range defaultConfigFiles {
+			if _, err := os.Stat(name); err == nil {
+				path = name
+				break
+			}
+		}
+		if path == "" {
+			return "", nil
+		}
+	}
+	c, err := parseConfig(path)
+	if err != nil {
+		return "", err
+	}
+	for name, value := range c.env() {
+		if _, ok := os.LookupEnv(name); !ok {
+			os.Setenv(name, value)
+		}
+	}
+	return path, nil
+}
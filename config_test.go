@@ -0,0 +1,115 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func writeConfig(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func Test_parseConfig(t *testing.T) {
+	t.Parallel()
+	yamlPath := writeConfig(t, "du.yaml", `
+journals: [DU, MP]
+limits:
+  new_acts: 5
+summarizer:
+  model: gpt-5-mini
+  temperature: 0.2
+targets:
+  mastodon:
+    server: https://mastodon.social
+    token: secret
+  bluesky:
+    handles: bluesky-handles.json
+`)
+	tomlPath := writeConfig(t, "du.toml", `
+journals = ["DU", "MP"]
+
+[limits]
+new_acts = 5
+
+[summarizer]
+model = "gpt-5-mini"
+temperature = 0.2
+
+[targets.mastodon]
+server = "https://mastodon.social"
+token = "secret"
+
+[targets.bluesky]
+handles = "bluesky-handles.json"
+`)
+	want := map[string]string{
+		"JOURNALS":               "DU,MP",
+		"MAX_NEW_ACTS":           "5",
+		"SUMMARIZER_MODEL":       "gpt-5-mini",
+		"SUMMARIZER_TEMPERATURE": "0.2",
+		"MASTODON_SERVER":        "https://mastodon.social",
+		"MASTODON_TOKEN":         "secret",
+		"BLUESKY_HANDLES":        "bluesky-handles.json",
+	}
+	for _, path := range []string{yamlPath, tomlPath} {
+		c, err := parseConfig(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := c.env(); !reflect.DeepEqual(got, want) {
+			t.Errorf("%s env = %v, want %v", filepath.Base(path), got, want)
+		}
+	}
+
+	for name, content := range map[string]string{
+		"typo.yaml":  "targets:\n  mastodon:\n    tokne: secret\n",
+		"typo.toml":  "[targets.mastodon]\ntokne = \"secret\"\n",
+		"bad.yaml":   "journals: DU\n",
+		"config.ini": "journals=DU\n",
+	} {
+		if _, err := parseConfig(writeConfig(t, name, content)); err == nil {
+			t.Errorf("parseConfig(%s) returned no error", name)
+		}
+	}
+}
+
+func Test_loadConfig(t *testing.T) {
+	t.Setenv("SUMMARIZER_MODEL", "from-env")
+	t.Setenv("SUMMARIZER_URL", "")
+	os.Unsetenv("SUMMARIZER_URL")
+	path := writeConfig(t, "du.yaml", "summarizer:\n  model: from-file\n  url: http://localhost:11434/v1\n")
+	if _, err := loadConfig(path); err != nil {
+		t.Fatal(err)
+	}
+	if got := os.Getenv("SUMMARIZER_MODEL"); got != "from-env" {
+		t.Errorf("SUMMARIZER_MODEL = %q, the environment should override the file", got)
+	}
+	if got := os.Getenv("SUMMARIZER_URL"); got != "http://localhost:11434/v1" {
+		t.Errorf("SUMMARIZER_URL = %q", got)
+	}
+
+	if path, err := loadConfig(""); err != nil || path != "" {
+		t.Errorf("loadConfig() without a config file = %q, %v", path, err)
+	}
+	if _, err := loadConfig(filepath.Join(t.TempDir(), "missing.yaml")); err == nil {
+		t.Errorf("missing config file should be an error")
+	}
+}
+
+func Test_exampleConfig(t *testing.T) {
+	t.Parallel()
+	c, err := parseConfig("du.example.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if env := c.env(); env["JOURNALS"] != "DU,MP" || env["SUMMARIZER_MODEL"] != "gpt-5-nano" || env["OTEL_TRACES_EXPORTER"] != "none" {
+		t.Errorf("env = %v", env)
+	}
+}
@@ -0,0 +1,208 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+	_ "time/tzdata" // the daemon runs in Polish time, also in minimal containers
+
+	log "github.com/sirupsen/logrus"
+)
+
+// schedule decides when the daemon checks for new acts. Acts are published on business days
+// so by default the daemon runs only within business hours on working days in Poland.
+type schedule struct {
+	interval time.Duration
+	// start and end are hours of the day, runs start at or after start and before end.
+	start, end int
+	// weekends allows runs on Saturdays and Sundays.
+	weekends bool
+	// holidays allows runs on Polish public holidays.
+	holidays bool
+	location *time.Location
+}
+
+// active reports whether a run may start at t.
+func (s schedule) active(t time.Time) bool {
+	t = t.In(s.location)
+	if t.Hour() < s.start || t.Hour() >= s.end {
+		return false
+	}
+	if !s.weekends && (t.Weekday() == time.Saturday || t.Weekday() == time.Sunday) {
+		return false
+	}
+	if !s.holidays && isPolishHoliday(t) {
+		return false
+	}
+	return true
+}
+
+// next returns when the run following the one started at t should start.
+func (s schedule) next(t time.Time) time.Time {
+	n := t.Add(s.interval)
+	if s.active(n) {
+		return n
+	}
+	local := n.In(s.location)
+	for i := 0; i <= 366; i++ {
+		start := time.Date(local.Year(), local.Month(), local.Day()+i, s.start, 0, 0, 0, s.location)
+		if start.After(t) && s.active(start) {
+			return start
+		}
+	}
+	return n
+}
+
+// parseHours parses business hours, e.g. "8-17".
+func parseHours(hours string) (start, end int, err error) {
+	from, to, ok := strings.Cut(hours, "-")
+	if !ok {
+		return 0, 0, fmt.Errorf("invalid hours %q, expected e.g. 8-17", hours)
+	}
+	start, errStart := strconv.Atoi(strings.TrimSpace(from))
+	end, errEnd := strconv.Atoi(strings.TrimSpace(to))
+	if errStart != nil || errEnd != nil || start < 0 || end > 24 || start >= end {
+		return 0, 0, fmt.Errorf("invalid hours %q, expected e.g. 8-17", hours)
+	}
+	return start, end, nil
+}
+
+// isPolishHoliday reports whether the day is a public holiday in Poland (dni wolne od pracy).
+func isPolishHoliday(t time.Time) bool {
+	month, day := t.Month(), t.Day()
+	switch {
+	case month == time.January && (day == 1 || day == 6),
+		month == time.May && (day == 1 || day == 3),
+		month == time.August && day == 15,
+		month == time.November && (day == 1 || day == 11),
+		month == time.December && (day == 25 || day == 26),
+		// Christmas Eve is a holiday since 2025
+		month == time.December && day == 24 && t.Year() >= 2025:
+		return true
+	}
+	easter := easterSunday(t.Year())
+	date := time.Date(t.Year(), month, day, 0, 0, 0, 0, time.UTC)
+	for _, offset := range []int{
+		0,  // Wielkanoc
+		1,  // Poniedziałek Wielkanocny
+		49, // Zielone Świątki
+		60, // Boże Ciało
+	} {
+		if date.Equal(easter.AddDate(0, 0, offset)) {
+			return true
+		}
+	}
+	return false
+}
+
+// easterSunday returns the date of Easter in the Gregorian calendar (anonymous Gregorian algorithm).
+func easterSunday(year int) time.Time {
+	a := year % 19
+	b, c := year/100, year%100
+	d, e := b/4, b%4
+	f := (b + 8) / 25
+	g := (b - f + 1) / 3
+	h := (19*a + b - d - g + 15) % 30
+	i, k := c/4, c%4
+	l := (32 + 2*e + 2*i - h - k) % 7
+	m := (a + 11*h + 22*l) / 451
+	month := (h + l - 7*m + 114) / 31
+	day := (h+l-7*m+114)%31 + 1
+	return time.Date(year, time.Month(month), day, 0, 0, 0, 0, time.UTC)
+}
+
+// daemon runs the bot on schedule until ctx is cancelled, a run in progress is always finished
+// so no act is left half published. A failed run is logged and the next one is scheduled as usual.
+func daemon(ctx context.Context, s schedule, run func(context.Context) error, now func() time.Time) {
+	at := now()
+	if !s.active(at) {
+		at = s.next(at)
+	}
+	for {
+		wait := at.Sub(now())
+		log.WithField("At", at.In(s.location).Format(time.DateTime)).Info("Next run")
+		select {
+		case <-ctx.Done():
+			log.Info("Stopped")
+			return
+		case <-time.After(wait):
+		}
+		started := now()
+		err := run(context.WithoutCancel(ctx))
+		logger := log.WithField("Took", now().Sub(started).Round(time.Second))
+		if err != nil {
+			runFailures.Inc()
+			logger.WithError(err).Error("Run failed")
+		} else {
+			logger.Info("Run finished")
+		}
+		if ctx.Err() != nil {
+			log.Info("Stopped")
+			return
+		}
+		at = s.next(started)
+	}
+}
+
+func daemonCommand(args []string) {
+	fs := flag.NewFlagSet("daemon", flag.ExitOnError)
+	interval := fs.Duration("interval", 15*time.Minute, "how often to check for new acts")
+	hours := fs.String("hours", "8-17", "business hours in Polish time, runs start within them")
+	weekends := fs.Bool("weekends", false, "run on weekends")
+	holidays := fs.Bool("holidays", false, "run on Polish public holidays")
+	addr := fs.String("addr", "localhost:9090", "address to serve metrics and health checks on, empty to disable")
+	fs.Parse(args)
+	start, end, err := parseHours(*hours)
+	if err != nil || *interval <= 0 {
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+		}
+		fs.Usage()
+		os.Exit(2)
+	}
+	location, err := time.LoadLocation("Europe/Warsaw")
+	if err != nil {
+		log.WithError(err).Fatal("Could not load time zone")
+	}
+	s := schedule{interval: *interval, start: start, end: end, weekends: *weekends, holidays: *holidays, location: location}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-ctx.Done()
+		// a second signal kills the daemon without waiting for the run to finish
+		stop()
+		log.Info("Shutting down after the current run")
+	}()
+	if *addr != "" {
+		js, err := enabledJournals()
+		if err != nil {
+			log.WithError(err).Fatal("Could not read journals")
+		}
+		go serveObservability(ctx, *addr, newArchive(js))
+	}
+	daemon(ctx, s, run, time.Now)
+}
+
+// serveObservability serves metrics and health checks of the daemon, it is not ready while shutting down.
+func serveObservability(ctx context.Context, addr string, a *archive) {
+	mux := http.NewServeMux()
+	observability(mux, func() error {
+		if ctx.Err() != nil {
+			return errors.New("shutting down")
+		}
+		return a.ready()
+	})
+	server := &http.Server{Addr: addr, Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	log.WithField("Addr", addr).Info("Serving metrics")
+	if err := server.ListenAndServe(); err != nil {
+		log.WithError(err).Error("Could not serve metrics")
+	}
+}
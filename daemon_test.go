@@ -0,0 +1,159 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func Test_isPolishHoliday(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		date string
+		want bool
+	}{
+		{date: "2024-01-01", want: true},
+		{date: "2024-01-02", want: false},
+		{date: "2024-03-31", want: true},  // Wielkanoc
+		{date: "2024-04-01", want: true},  // Poniedziałek Wielkanocny
+		{date: "2024-05-03", want: true},  // Święto Konstytucji 3 Maja
+		{date: "2024-05-30", want: true},  // Boże Ciało
+		{date: "2025-04-21", want: true},  // Poniedziałek Wielkanocny
+		{date: "2025-06-19", want: true},  // Boże Ciało
+		{date: "2026-04-06", want: true},  // Poniedziałek Wielkanocny
+		{date: "2024-12-24", want: false}, // Wigilia is a holiday since 2025
+		{date: "2025-12-24", want: true},
+		{date: "2024-11-11", want: true},
+		{date: "2024-11-12", want: false},
+	}
+	for _, tt := range tests {
+		d, err := time.Parse(time.DateOnly, tt.date)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := isPolishHoliday(d); got != tt.want {
+			t.Errorf("isPolishHoliday(%s) = %v, want %v", tt.date, got, tt.want)
+		}
+	}
+}
+
+func Test_scheduleNext(t *testing.T) {
+	t.Parallel()
+	warsaw, err := time.LoadLocation("Europe/Warsaw")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := schedule{interval: 15 * time.Minute, start: 8, end: 17, location: warsaw}
+	at := func(s string) time.Time {
+		t.Helper()
+		v, err := time.ParseInLocation(time.DateTime, s, warsaw)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return v
+	}
+	tests := []struct {
+		now  string
+		want string
+	}{
+		{now: "2024-05-06 10:00:00", want: "2024-05-06 10:15:00"},
+		{now: "2024-05-06 16:50:00", want: "2024-05-07 08:00:00"},
+		{now: "2024-05-06 05:00:00", want: "2024-05-06 08:00:00"},
+		// Friday evening waits for Monday
+		{now: "2024-05-10 16:50:00", want: "2024-05-13 08:00:00"},
+		// 1 May is a holiday, 2 May is a working day, 3 May is a holiday
+		{now: "2024-04-30 16:55:00", want: "2024-05-02 08:00:00"},
+		{now: "2024-05-02 16:55:00", want: "2024-05-06 08:00:00"},
+		// Christmas
+		{now: "2025-12-23 16:55:00", want: "2025-12-29 08:00:00"},
+	}
+	for _, tt := range tests {
+		if got := s.next(at(tt.now)).In(warsaw).Format(time.DateTime); got != tt.want {
+			t.Errorf("next(%s) = %s, want %s", tt.now, got, tt.want)
+		}
+	}
+
+	all := schedule{interval: time.Hour, start: 0, end: 24, weekends: true, holidays: true, location: warsaw}
+	if got := all.next(at("2024-05-03 23:30:00")).In(warsaw).Format(time.DateTime); got != "2024-05-04 00:30:00" {
+		t.Errorf("next() = %s", got)
+	}
+}
+
+func Test_parseHours(t *testing.T) {
+	t.Parallel()
+	if start, end, err := parseHours("8-17"); err != nil || start != 8 || end != 17 {
+		t.Errorf("parseHours(8-17) = %d, %d, %v", start, end, err)
+	}
+	for _, hours := range []string{"8", "17-8", "a-b", "0-25"} {
+		if _, _, err := parseHours(hours); err == nil {
+			t.Errorf("parseHours(%s) returned no error", hours)
+		}
+	}
+}
+
+func Test_daemon(t *testing.T) {
+	t.Parallel()
+	s := schedule{interval: 10 * time.Millisecond, start: 0, end: 24, weekends: true, holidays: true, location: time.UTC}
+	ctx, cancel := context.WithCancel(context.Background())
+	var runs atomic.Int32
+	finished := make(chan struct{})
+	go func() {
+		daemon(ctx, s, func(runCtx context.Context) error {
+			if runs.Add(1) == 3 {
+				// shutdown is requested during the run
+				cancel()
+				time.Sleep(20 * time.Millisecond)
+				if runCtx.Err() != nil {
+					t.Error("run was cancelled")
+				}
+			}
+			return nil
+		}, time.Now)
+		close(finished)
+	}()
+	select {
+	case <-finished:
+	case <-time.After(5 * time.Second):
+		t.Fatal("daemon did not stop")
+	}
+	if runs.Load() != 3 {
+		t.Errorf("runs = %d, want 3", runs.Load())
+	}
+}
+
+func Test_daemonFailedRun(t *testing.T) {
+	t.Parallel()
+	s := schedule{interval: 10 * time.Millisecond, start: 0, end: 24, weekends: true, holidays: true, location: time.UTC}
+	ctx, cancel := context.WithCancel(context.Background())
+	var runs atomic.Int32
+	failures := failedRuns()
+	finished := make(chan struct{})
+	go func() {
+		daemon(ctx, s, func(context.Context) error {
+			if runs.Add(1) == 3 {
+				cancel()
+			}
+			return errors.New("Dz.U. is down")
+		}, time.Now)
+		close(finished)
+	}()
+	select {
+	case <-finished:
+	case <-time.After(5 * time.Second):
+		t.Fatal("daemon did not stop")
+	}
+	if runs.Load() != 3 {
+		t.Errorf("runs = %d, want 3, the daemon should keep running after a failure", runs.Load())
+	}
+	if got := failedRuns() - failures; got != 3 {
+		t.Errorf("failures = %v, want 3", got)
+	}
+}
+
+func failedRuns() float64 {
+	return testutil.ToFloat64(runFailures)
+}
@@ -0,0 +1,199 @@
+package main
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Grammatical cases of Polish names, vocative is not used in acts.
+const (
+	nominative = iota
+	genitive
+	dative
+	accusative
+	instrumental
+	locative
+	cases
+)
+
+type gender int
+
+const (
+	masculinePersonal gender = iota
+	masculine
+	feminine
+	neuter
+)
+
+type noun struct {
+	gender gender
+	forms  [cases]string
+}
+
+// nouns are heads of government body names by their genitive form used in titles, e.g. "Ministra" in
+// "Rozporządzenie Ministra Finansów". Adjectives agreeing with the head are declined by rules.
+var nouns = map[string]noun{}
+
+func init() {
+	for _, n := range []noun{
+		{masculinePersonal, [cases]string{"Minister", "Ministra", "Ministrowi", "Ministra", "Ministrem", "Ministrze"}},
+		{masculinePersonal, [cases]string{"Prezes", "Prezesa", "Prezesowi", "Prezesa", "Prezesem", "Prezesie"}},
+		{masculinePersonal, [cases]string{"Prezydent", "Prezydenta", "Prezydentowi", "Prezydenta", "Prezydentem", "Prezydencie"}},
+		{masculinePersonal, [cases]string{"Marszałek", "Marszałka", "Marszałkowi", "Marszałka", "Marszałkiem", "Marszałku"}},
+		{masculinePersonal, [cases]string{"Inspektor", "Inspektora", "Inspektorowi", "Inspektora", "Inspektorem", "Inspektorze"}},
+		{masculinePersonal, [cases]string{"Rzecznik", "Rzecznika", "Rzecznikowi", "Rzecznika", "Rzecznikiem", "Rzeczniku"}},
+		{masculinePersonal, [cases]string{"Komendant", "Komendanta", "Komendantowi", "Komendanta", "Komendantem", "Komendancie"}},
+		{masculinePersonal, [cases]string{"Szef", "Szefa", "Szefowi", "Szefa", "Szefem", "Szefie"}},
+		{masculine, [cases]string{"Urząd", "Urzędu", "Urzędowi", "Urząd", "Urzędem", "Urzędzie"}},
+		{masculine, [cases]string{"Bank", "Banku", "Bankowi", "Bank", "Bankiem", "Banku"}},
+		{masculine, [cases]string{"Trybunał", "Trybunału", "Trybunałowi", "Trybunał", "Trybunałem", "Trybunale"}},
+		{masculine, [cases]string{"Sąd", "Sądu", "Sądowi", "Sąd", "Sądem", "Sądzie"}},
+		{neuter, [cases]string{"Biuro", "Biura", "Biuru", "Biuro", "Biurem", "Biurze"}},
+		{feminine, [cases]string{"Izba", "Izby", "Izbie", "Izbę", "Izbą", "Izbie"}},
+		{feminine, [cases]string{"Komisja", "Komisji", "Komisji", "Komisję", "Komisją", "Komisji"}},
+		{feminine, [cases]string{"Straż", "Straży", "Straży", "Straż", "Strażą", "Straży"}},
+		{feminine, [cases]string{"Agencja", "Agencji", "Agencji", "Agencję", "Agencją", "Agencji"}},
+		{feminine, [cases]string{"Inspekcja", "Inspekcji", "Inspekcji", "Inspekcję", "Inspekcją", "Inspekcji"}},
+		{feminine, [cases]string{"Rada", "Rady", "Radzie", "Radę", "Radą", "Radzie"}},
+	} {
+		nouns[n.forms[genitive]] = n
+	}
+}
+
+// adjective declines an adjective in genitive agreeing with a noun of the gender, e.g. "Centralnego" or
+// "Najwyższej", ok is false when the word is not such an adjective.
+func adjective(word string, g gender) (forms [cases]string, ok bool) {
+	if g == feminine {
+		// Polskiej, Wyborczej
+		stem, found := strings.CutSuffix(word, "ej")
+		if !found || stem == "" {
+			return forms, false
+		}
+		if strings.HasSuffix(stem, "ki") || strings.HasSuffix(stem, "gi") {
+			stem = strings.TrimSuffix(stem, "i")
+		}
+		return [cases]string{stem + "a", word, word, stem + "ą", stem + "ą", word}, true
+	}
+	// Centralnego, Polskiego, Ostatniego
+	stem, found := strings.CutSuffix(word, "ego")
+	if !found || stem == "" {
+		return forms, false
+	}
+	y, e, emu := "y", "e", "emu"
+	switch {
+	case strings.HasSuffix(stem, "k") || strings.HasSuffix(stem, "g"):
+		y, e, emu = "i", "ie", "iemu"
+	case strings.HasSuffix(stem, "i"):
+		y = ""
+	}
+	forms = [cases]string{stem + y, word, stem + emu, stem + y, stem + y + "m", stem + y + "m"}
+	switch g {
+	case masculinePersonal:
+		forms[accusative] = word
+	case neuter:
+		forms[nominative], forms[accusative] = stem+e, stem+e
+	}
+	return forms, true
+}
+
+// inflections returns the name declined in all cases, the name first. Only the head noun, e.g. "Ministra",
+// and adjectives agreeing with it are declined, "Ministra Finansów" gives "Minister Finansów", "Ministrowi
+// Finansów" and so on. Names without a known head are returned as they are.
+func inflections(name string) []string {
+	words := strings.Split(name, " ")
+	head := -1
+	for i, w := range words {
+		if _, ok := nouns[w]; ok {
+			head = i
+			break
+		}
+	}
+	if head < 0 {
+		return []string{name}
+	}
+	n := nouns[words[head]]
+	declined := make([][cases]string, len(words))
+	// adjectives before the head must agree, e.g. "Głównego Inspektora"
+	for i := 0; i < head; i++ {
+		forms, ok := adjective(words[i], n.gender)
+		if !ok {
+			return []string{name}
+		}
+		declined[i] = forms
+	}
+	declined[head] = n.forms
+	// adjectives after the head agree until the first other word, e.g. "Banku Polskiego" but not "Prezydenta Rzeczypospolitej Polskiej"
+	last := head
+	for i := head + 1; i < len(words); i++ {
+		forms, ok := adjective(words[i], n.gender)
+		if !ok {
+			break
+		}
+		declined[i], last = forms, i
+	}
+
+	result := []string{name}
+	for c := 0; c < cases; c++ {
+		form := make([]string, len(words))
+		copy(form, words)
+		for i := 0; i <= last; i++ {
+			form[i] = declined[i][c]
+		}
+		if s := strings.Join(form, " "); !containsFold(result, s) {
+			result = append(result, s)
+		}
+	}
+	return result
+}
+
+// foldCase lowercases the text keeping byte offsets, e.g. of "MINISTRA FINANSÓW" in PDF text.
+func foldCase(s string) string {
+	var b strings.Builder
+	b.Grow(len(s))
+	for _, r := range s {
+		if l := unicode.ToLower(r); utf8.RuneLen(l) == utf8.RuneLen(r) {
+			r = l
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// nameLinker finds names in any grammatical case regardless of letter case.
+type nameLinker struct {
+	linker *linker
+	// names maps linker names, the forms, to indexes of the names.
+	names []int
+}
+
+// newNameLinker links the names and their inflections, extra returns more forms of a name, e.g. irregular ones.
+func newNameLinker(names []string, extra func(name int) []string) *nameLinker {
+	l := &nameLinker{}
+	var forms []string
+	for i, name := range names {
+		variants := inflections(name)
+		if extra != nil {
+			variants = append(variants, extra(i)...)
+		}
+		for _, v := range variants {
+			forms = append(forms, foldCase(v))
+			l.names = append(l.names, i)
+		}
+	}
+	l.linker = newLinker(forms)
+	return l
+}
+
+// match returns leftmost-longest non-overlapping matches of the names, Name is the index of the name.
+func (l *nameLinker) match(text string, accepted func(name int) bool) []linkMatch {
+	var filter func(int) bool
+	if accepted != nil {
+		filter = func(form int) bool { return accepted(l.names[form]) }
+	}
+	matches := l.linker.match(foldCase(text), filter)
+	for i := range matches {
+		matches[i].Name = l.names[matches[i].Name]
+	}
+	return matches
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func Test_inflections(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name string
+		want []string
+	}{
+		{"Ministra Finansów", []string{"Ministra Finansów", "Minister Finansów", "Ministrowi Finansów", "Ministrem Finansów", "Ministrze Finansów"}},
+		{"Centralnego Biura Antykorupcyjnego", []string{"Centralnego Biura Antykorupcyjnego", "Centralne Biuro Antykorupcyjne",
+			"Centralnemu Biuru Antykorupcyjnemu", "Centralnym Biurem Antykorupcyjnym", "Centralnym Biurze Antykorupcyjnym"}},
+		{"Narodowego Banku Polskiego", []string{"Narodowego Banku Polskiego", "Narodowy Bank Polski", "Narodowemu Bankowi Polskiemu",
+			"Narodowym Bankiem Polskim", "Narodowym Banku Polskim"}},
+		{"Najwyższej Izby Kontroli", []string{"Najwyższej Izby Kontroli", "Najwyższa Izba Kontroli", "Najwyższej Izbie Kontroli",
+			"Najwyższą Izbę Kontroli", "Najwyższą Izbą Kontroli"}},
+		// only the head and adjectives agreeing with it are declined
+		{"Prezydenta Rzeczypospolitej Polskiej", []string{"Prezydenta Rzeczypospolitej Polskiej", "Prezydent Rzeczypospolitej Polskiej",
+			"Prezydentowi Rzeczypospolitej Polskiej", "Prezydentem Rzeczypospolitej Polskiej", "Prezydencie Rzeczypospolitej Polskiej"}},
+		{"Głównego Inspektora Transportu Drogowego", []string{"Głównego Inspektora Transportu Drogowego", "Główny Inspektor Transportu Drogowego",
+			"Głównemu Inspektorowi Transportu Drogowego", "Głównym Inspektorem Transportu Drogowego", "Głównym Inspektorze Transportu Drogowego"}},
+		{"Państwowej Komisji Wyborczej", []string{"Państwowej Komisji Wyborczej", "Państwowa Komisja Wyborcza", "Państwową Komisję Wyborczą", "Państwową Komisją Wyborczą"}},
+		{"Sejmu", []string{"Sejmu"}},
+	}
+	for _, tt := range tests {
+		if got := inflections(tt.name); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("inflections(%q) =\n%q, want\n%q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func Test_nameLinker(t *testing.T) {
+	t.Parallel()
+	l := newNameLinker([]string{"Ministra Zdrowia", "Głównego Urzędu Statystycznego"}, func(name int) []string {
+		if name == 1 {
+			return []string{"GUS"}
+		}
+		return nil
+	})
+	text := "ROZPORZĄDZENIE MINISTRA ZDROWIA. Minister Zdrowia przekazuje Głównemu Urzędowi Statystycznemu i GUS dane."
+	var got []string
+	for _, m := range l.match(text, nil) {
+		got = append(got, text[m.Start:m.End])
+	}
+	want := []string{"MINISTRA ZDROWIA", "Minister Zdrowia", "Głównemu Urzędowi Statystycznemu", "GUS"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("match() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,478 @@
+package main
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	dictionaryFile = "dictionaries.json"
+	// dictionaryVersion is the supported version of the file format.
+	dictionaryVersion = 1
+)
+
+// embeddedDictionary is used when the dictionary file is missing, e.g. when the binary runs outside the repository.
+//
+//go:embed dictionaries.json
+var embeddedDictionary []byte
+
+// dictionary holds institution handles inserted into act titles and emojis prepended to them.
+type dictionary struct {
+	Version      int           `json:"version"`
+	Institutions []institution `json:"institutions"`
+	Emojis       []emojiRule   `json:"emojis"`
+
+	// names are the distinct institution names found by linker in all grammatical cases.
+	names  []string
+	linker *nameLinker
+}
+
+type institution struct {
+	// Name is the institution as it appears in act titles, e.g. "Ministra Finansów".
+	Name    string `json:"name"`
+	Twitter string `json:"twitter,omitempty"`
+	// Bluesky is the handle without @, e.g. "mf.gov.pl".
+	Bluesky string `json:"bluesky,omitempty"`
+	// Forms are forms of the name not covered by the declension table, other cases are found from the name.
+	Forms []string `json:"forms,omitempty"`
+	scope
+}
+
+type emojiRule struct {
+	// Prefix is the first word of the title, e.g. "Obwieszczenie".
+	Prefix string `json:"prefix"`
+	Emoji  string `json:"emoji"`
+	scope
+}
+
+// scope limits an entry to acts of some journals, all when empty, issued between From and To,
+// e.g. a ministry renamed or a handle that changed owner.
+type scope struct {
+	Journals []string `json:"journals,omitempty"`
+	From     *date    `json:"from,omitempty"`
+	To       *date    `json:"to,omitempty"`
+}
+
+func (s scope) applies(j *journal, at time.Time) bool {
+	if len(s.Journals) > 0 && !containsFold(s.Journals, j.Code) {
+		return false
+	}
+	return (s.From == nil || !at.Before(s.From.Time)) && (s.To == nil || !at.After(s.To.Time))
+}
+
+// overlaps reports whether an act could be in both scopes.
+func (s scope) overlaps(o scope) bool {
+	if len(s.Journals) > 0 && len(o.Journals) > 0 {
+		shared := false
+		for _, code := range s.Journals {
+			shared = shared || containsFold(o.Journals, code)
+		}
+		if !shared {
+			return false
+		}
+	}
+	return (s.From == nil || o.To == nil || !s.From.After(o.To.Time)) && (o.From == nil || s.To == nil || !o.From.After(s.To.Time))
+}
+
+func (s scope) validate() error {
+	var errs []error
+	for _, code := range s.Journals {
+		if _, err := journalByCode(code); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if s.From != nil && s.To != nil && s.To.Before(s.From.Time) {
+		errs = append(errs, fmt.Errorf("to %s is before from %s", s.To.Format(time.DateOnly), s.From.Format(time.DateOnly)))
+	}
+	return errors.Join(errs...)
+}
+
+var (
+	// twitterHandleRegexp follows Twitter username rules, up to 15 letters, digits and underscores.
+	twitterHandleRegexp = regexp.MustCompile(`^@[A-Za-z0-9_]{1,15}$`)
+	// blueskyHandleRegexp matches a domain name, Bluesky handles are domains.
+	blueskyHandleRegexp = regexp.MustCompile(`^([A-Za-z0-9]([A-Za-z0-9-]{0,61}[A-Za-z0-9])?\.)+[A-Za-z]([A-Za-z0-9-]{0,61}[A-Za-z0-9])?$`)
+)
+
+// validKey reports whether the name has no surrounding or repeated whitespace, such keys never match titles.
+func validKey(name string) bool {
+	return name != "" && strings.Join(strings.Fields(name), " ") == name
+}
+
+// validate returns all problems of the dictionary.
+func (d *dictionary) validate() error {
+	var errs []error
+	if d.Version != dictionaryVersion {
+		errs = append(errs, fmt.Errorf("unsupported version %d, expected %d", d.Version, dictionaryVersion))
+	}
+	forms := map[string]string{}
+	for i, in := range d.Institutions {
+		invalid := func(format string, args ...any) {
+			errs = append(errs, fmt.Errorf("institution %q: %s", in.Name, fmt.Sprintf(format, args...)))
+		}
+		if !validKey(in.Name) {
+			invalid("name must not be empty or have extra whitespace")
+		}
+		if in.Twitter == "" && in.Bluesky == "" {
+			invalid("no handle")
+		}
+		if in.Twitter != "" && !twitterHandleRegexp.MatchString(in.Twitter) {
+			invalid("invalid Twitter handle %q", in.Twitter)
+		}
+		if in.Bluesky != "" && !blueskyHandleRegexp.MatchString(in.Bluesky) {
+			invalid("invalid Bluesky handle %q, expected a domain without @", in.Bluesky)
+		}
+		for _, form := range in.Forms {
+			if !validKey(form) {
+				invalid("form %q must not be empty or have extra whitespace", form)
+			}
+		}
+		if err := in.scope.validate(); err != nil {
+			invalid("%v", err)
+		}
+		// a form of two institutions could not be linked to either of them
+		for _, form := range in.forms() {
+			if name, ok := forms[foldCase(form)]; ok && name != in.Name {
+				invalid("%q is also a form of %q", form, name)
+			}
+			forms[foldCase(form)] = in.Name
+		}
+		for _, other := range d.Institutions[:i] {
+			if other.Name == in.Name && other.scope.overlaps(in.scope) {
+				invalid("duplicate name")
+				break
+			}
+		}
+	}
+	for i, e := range d.Emojis {
+		invalid := func(format string, args ...any) {
+			errs = append(errs, fmt.Errorf("emoji %q: %s", e.Prefix, fmt.Sprintf(format, args...)))
+		}
+		if !validKey(e.Prefix) || strings.Contains(e.Prefix, " ") {
+			invalid("prefix must be a single word")
+		}
+		if e.Emoji == "" || strings.IndexFunc(e.Emoji, func(r rune) bool { return unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.IsSpace(r) }) >= 0 {
+			invalid("invalid emoji %q", e.Emoji)
+		}
+		if err := e.scope.validate(); err != nil {
+			invalid("%v", err)
+		}
+		for _, other := range d.Emojis[:i] {
+			if other.Prefix == e.Prefix && other.scope.overlaps(e.scope) {
+				invalid("duplicate prefix")
+				break
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func parseDictionary(data []byte) (*dictionary, error) {
+	d := &dictionary{}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(d); err != nil {
+		return nil, err
+	}
+	if err := d.validate(); err != nil {
+		return nil, err
+	}
+	seen := map[string]bool{}
+	for _, in := range d.Institutions {
+		if !seen[in.Name] {
+			seen[in.Name] = true
+			d.names = append(d.names, in.Name)
+		}
+	}
+	d.linker = newNameLinker(d.names, func(name int) []string {
+		var forms []string
+		for _, in := range d.Institutions {
+			if in.Name == d.names[name] {
+				forms = append(forms, in.Forms...)
+			}
+		}
+		return forms
+	})
+	return d, nil
+}
+
+// forms returns the name in all grammatical cases and the extra forms.
+func (in *institution) forms() []string {
+	return append(inflections(in.Name), in.Forms...)
+}
+
+// canonical returns the dictionary name of the institution in any grammatical case, e.g. "Ministra Finansów"
+// for "Minister Finansów".
+func (d *dictionary) canonical(name string) (string, bool) {
+	name = strings.Join(strings.Fields(name), " ")
+	if m := d.linker.match(name, nil); len(m) == 1 && m[0].Start == 0 && m[0].End == len(name) {
+		return d.names[m[0].Name], true
+	}
+	return "", false
+}
+
+// institution returns the institution with the name applying to the act, nil when none does.
+func (d *dictionary) institution(j *journal, at time.Time, name string) *institution {
+	for i, in := range d.Institutions {
+		if in.Name == name && in.applies(j, at) {
+			return &d.Institutions[i]
+		}
+	}
+	return nil
+}
+
+// institutionMatch is an institution found in a text, Start and End are byte offsets.
+type institutionMatch struct {
+	Institution *institution
+	Start, End  int
+}
+
+// match returns institutions applying to the act found in the text. The longest name wins and matches do not
+// overlap, so "Ministra Klimatu i Środowiska" is never linked as "Ministra Klimatu".
+func (d *dictionary) match(j *journal, at time.Time, text string) []institutionMatch {
+	accepted := func(name int) bool { return d.institution(j, at, d.names[name]) != nil }
+	var matches []institutionMatch
+	for _, m := range d.linker.match(text, accepted) {
+		matches = append(matches, institutionMatch{Institution: d.institution(j, at, d.names[m.Name]), Start: m.Start, End: m.End})
+	}
+	return matches
+}
+
+// authorities returns names of institutions issuing the act found in the title between the act type and
+// the signing date, e.g. "Ministra Finansów" in "Rozporządzenie Ministra Finansów z dnia …", or anywhere
+// in titles without a date.
+func (d *dictionary) authorities(j *journal, title string) []string {
+	at := actDate(title)
+	start, end := 0, len(title)
+	if m := titleRegexp.FindStringSubmatchIndex(title); m != nil {
+		start, end = m[4], m[5]
+	}
+	var names []string
+	for _, m := range d.match(j, at, title) {
+		if m.Start >= start && m.End <= end && !slices.Contains(names, m.Institution.Name) {
+			names = append(names, m.Institution.Name)
+		}
+	}
+	return names
+}
+
+// emoji returns the emoji rule matching the first word of the title.
+func (d *dictionary) emoji(j *journal, at time.Time, title string) *emojiRule {
+	for i, e := range d.Emojis {
+		if strings.HasPrefix(title, e.Prefix) && e.applies(j, at) {
+			return &d.Emojis[i]
+		}
+	}
+	return nil
+}
+
+// twitterHandles replaces institutions in the title with their Twitter handles.
+func (d *dictionary) twitterHandles(j *journal, at time.Time, title string) string {
+	matches := d.match(j, at, title)
+	return replace(title, linkMatches(matches), func(m linkMatch) string { return matches[m.Name].Institution.Twitter })
+}
+
+// linkMatches converts institution matches for replace, Name is the index of the institution match.
+func linkMatches(matches []institutionMatch) []linkMatch {
+	result := make([]linkMatch, len(matches))
+	for i, m := range matches {
+		result[i] = linkMatch{Name: i, Start: m.Start, End: m.End}
+	}
+	return result
+}
+
+// addEmoji prepends the emoji matching the first word of the title.
+func (d *dictionary) addEmoji(j *journal, at time.Time, title string) string {
+	if e := d.emoji(j, at, title); e != nil {
+		return e.Emoji + title
+	}
+	return title
+}
+
+// blueskyHandles maps institution names to Bluesky handles.
+func (d *dictionary) blueskyHandles(j *journal, at time.Time) map[string]string {
+	handles := map[string]string{}
+	for _, in := range d.Institutions {
+		if in.Bluesky != "" && in.applies(j, at) {
+			handles[in.Name] = in.Bluesky
+		}
+	}
+	return handles
+}
+
+// actDate returns the signing date from the title, entries are chosen for the date the act was issued.
+// Titles without a date get entries valid today.
+func actDate(title string) time.Time {
+	a := Act{Title: title}
+	a.parseTitle()
+	if a.Signed != nil {
+		return a.Signed.Time
+	}
+	return time.Now()
+}
+
+// dictionarySource reads the dictionary file again when it changes so entries can be fixed without a rebuild.
+type dictionarySource struct {
+	// path is the dictionary file, DICTIONARIES or dictionaries.json when empty.
+	path    string
+	mu      sync.Mutex
+	version fileVersion
+	current *dictionary
+}
+
+var dictionaries = &dictionarySource{}
+
+func (s *dictionarySource) file() string {
+	if s.path != "" {
+		return s.path
+	}
+	if path := os.Getenv("DICTIONARIES"); path != "" {
+		return path
+	}
+	return dictionaryFile
+}
+
+// get returns the current dictionary. An invalid file is reported and the last valid
+// dictionary is kept, the embedded one when the file was never valid.
+func (s *dictionarySource) get() *dictionary {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	path := s.file()
+	var version fileVersion
+	if info, err := os.Stat(path); err == nil {
+		version = fileVersion{modTime: info.ModTime(), size: info.Size()}
+	}
+	if s.current != nil && version == s.version {
+		return s.current
+	}
+	s.version = version
+	d, err := loadDictionary(path)
+	if err != nil {
+		log.WithError(err).WithField("Path", path).Error("Invalid dictionary, keeping the previous one")
+		if s.current == nil {
+			s.current, _ = parseDictionary(embeddedDictionary)
+		}
+		return s.current
+	}
+	if s.current != nil {
+		log.WithField("Path", path).Info("Dictionary reloaded")
+	}
+	s.current = d
+	return d
+}
+
+// loadDictionary reads and validates the dictionary file, the embedded one when the file does not exist.
+func loadDictionary(path string) (*dictionary, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		data = embeddedDictionary
+	} else if err != nil {
+		return nil, err
+	}
+	d, err := parseDictionary(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid dictionary %s: %w", path, err)
+	}
+	return d, nil
+}
+
+// String describes the scope, e.g. "MP, to 2023-12-12", empty when the entry always applies.
+func (s scope) String() string {
+	var parts []string
+	if len(s.Journals) > 0 {
+		parts = append(parts, strings.Join(s.Journals, ","))
+	}
+	if s.From != nil {
+		parts = append(parts, "from "+s.From.Format(time.DateOnly))
+	}
+	if s.To != nil {
+		parts = append(parts, "to "+s.To.Format(time.DateOnly))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// entryUsage counts acts a dictionary entry was applied to.
+type entryUsage struct {
+	label string
+	count int
+	// last is the newest act the entry was applied to.
+	last string
+}
+
+// dictionaryUsage prints how many of the acts, newest first, each dictionary entry was applied to, unused entries last.
+func dictionaryUsage(w io.Writer, d *dictionary, acts []archivedAct) {
+	institutions := make([]entryUsage, len(d.Institutions))
+	institutionUsage := map[*institution]*entryUsage{}
+	for i, in := range d.Institutions {
+		label := strings.TrimSpace(strings.Join([]string{in.Name, in.Twitter, in.Bluesky}, " "))
+		institutions[i].label = withScope(label, in.scope)
+		institutionUsage[&d.Institutions[i]] = &institutions[i]
+	}
+	emojis := make([]entryUsage, len(d.Emojis))
+	emojiUsage := map[*emojiRule]*entryUsage{}
+	for i, e := range d.Emojis {
+		emojis[i].label = withScope(e.Emoji+" "+e.Prefix, e.scope)
+		emojiUsage[&d.Emojis[i]] = &emojis[i]
+	}
+	use := func(u *entryUsage, header string) {
+		if u.count == 0 {
+			u.last = header
+		}
+		u.count++
+	}
+	for _, a := range acts {
+		j, err := journalByCode(a.Journal)
+		if err != nil {
+			continue
+		}
+		at := actDate(a.Title)
+		header := j.header(a.Year, a.Pos)
+		seen := map[*institution]bool{}
+		for _, m := range d.match(j, at, a.Title) {
+			if !seen[m.Institution] {
+				seen[m.Institution] = true
+				use(institutionUsage[m.Institution], header)
+			}
+		}
+		if e := d.emoji(j, at, a.Title); e != nil {
+			use(emojiUsage[e], header)
+		}
+	}
+
+	fmt.Fprintf(w, "Dictionary entries used in the last %d acts\n", len(acts))
+	for _, section := range []struct {
+		name    string
+		entries []entryUsage
+	}{{"Institutions", institutions}, {"Emojis", emojis}} {
+		sort.SliceStable(section.entries, func(a, b int) bool { return section.entries[a].count > section.entries[b].count })
+		fmt.Fprintf(w, "\n%s:\n", section.name)
+		for _, u := range section.entries {
+			if u.count == 0 {
+				fmt.Fprintf(w, "%5d  %s\n", u.count, u.label)
+				continue
+			}
+			fmt.Fprintf(w, "%5d  %s, last in %s\n", u.count, u.label, u.last)
+		}
+	}
+}
+
+func withScope(label string, s scope) string {
+	if scope := s.String(); scope != "" {
+		return label + " (" + scope + ")"
+	}
+	return label
+}
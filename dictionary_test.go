@@ -0,0 +1,217 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_dictionaryFile(t *testing.T) {
+	t.Parallel()
+	if _, err := loadDictionary(dictionaryFile); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func Test_parseDictionary(t *testing.T) {
+	t.Parallel()
+	tests := map[string]string{
+		"version":          `{"version": 2}`,
+		"unknown field":    `{"version": 1, "institutions": [{"name": "Ministra Zdrowia", "twiter": "@MZ_GOV_PL"}]}`,
+		"trailing space":   `{"version": 1, "institutions": [{"name": "Ministra Finansów ", "twitter": "@MF_gov_PL"}]}`,
+		"no handle":        `{"version": 1, "institutions": [{"name": "Ministra Zdrowia"}]}`,
+		"twitter handle":   `{"version": 1, "institutions": [{"name": "Ministra Zdrowia", "twitter": "MZ_GOV_PL"}]}`,
+		"long handle":      `{"version": 1, "institutions": [{"name": "Ministra Zdrowia", "twitter": "@Ministerstwo_Zdrowia"}]}`,
+		"bluesky handle":   `{"version": 1, "institutions": [{"name": "Ministra Zdrowia", "bluesky": "@mz.gov.pl"}]}`,
+		"unknown journal":  `{"version": 1, "institutions": [{"name": "Ministra Zdrowia", "twitter": "@MZ_GOV_PL", "journals": ["DZ"]}]}`,
+		"reversed range":   `{"version": 1, "institutions": [{"name": "Ministra Zdrowia", "twitter": "@MZ_GOV_PL", "from": "2024-01-01", "to": "2023-01-01"}]}`,
+		"duplicate":        `{"version": 1, "institutions": [{"name": "Ministra Zdrowia", "twitter": "@MZ_GOV_PL"}, {"name": "Ministra Zdrowia", "twitter": "@MZ", "journals": ["MP"]}]}`,
+		"overlapping":      `{"version": 1, "institutions": [{"name": "Ministra Zdrowia", "twitter": "@MZ_GOV_PL", "to": "2024-01-01"}, {"name": "Ministra Zdrowia", "twitter": "@MZ", "from": "2024-01-01"}]}`,
+		"emoji prefix":     `{"version": 1, "emojis": [{"prefix": "Umowa międzynarodowa", "emoji": "🤝"}]}`,
+		"emoji":            `{"version": 1, "emojis": [{"prefix": "Umowa", "emoji": "U"}]}`,
+		"shared form":      `{"version": 1, "institutions": [{"name": "Centralnego Biura Antykorupcyjnego", "twitter": "@CBAgovPL"}, {"name": "Centralnym Biurze Antykorupcyjnym", "twitter": "@CBA"}]}`,
+		"extra form":       `{"version": 1, "institutions": [{"name": "Ministra Zdrowia", "twitter": "@MZ_GOV_PL", "forms": ["MZ "]}]}`,
+		"duplicate prefix": `{"version": 1, "emojis": [{"prefix": "Umowa", "emoji": "🤝"}, {"prefix": "Umowa", "emoji": "📜"}]}`,
+	}
+	for name, data := range tests {
+		if _, err := parseDictionary([]byte(data)); err == nil {
+			t.Errorf("%s: parseDictionary() returned no error", name)
+		}
+	}
+
+	d, err := parseDictionary([]byte(`{"version": 1, "institutions": [
+		{"name": "Ministra Zdrowia", "twitter": "@MZ_GOV_PL", "to": "2023-12-31"},
+		{"name": "Ministra Zdrowia", "bluesky": "mz.gov.pl", "from": "2024-01-01", "to": "2029-12-31"},
+		{"name": "Ministra Zdrowia", "twitter": "@MZ", "journals": ["DU"], "from": "2030-01-01"},
+		{"name": "Ministra Zdrowia", "twitter": "@MZ_MP", "journals": ["MP"], "from": "2030-01-01"}
+	]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(d.Institutions) != 4 {
+		t.Errorf("institutions = %v", d.Institutions)
+	}
+}
+
+func Test_dictionaryScope(t *testing.T) {
+	t.Parallel()
+	d, err := loadDictionary(dictionaryFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		journal *journal
+		title   string
+		want    string
+	}{
+		{dziennikUstaw, "Rozporządzenie Ministra Nauki i Szkolnictwa Wyższego z dnia 5 maja 2022 r.", "Rozporządzenie @MEiN_gov_PL z dnia 5 maja 2022 r."},
+		{dziennikUstaw, "Rozporządzenie Ministra Nauki i Szkolnictwa Wyższego z dnia 5 maja 2025 r.", "Rozporządzenie Ministra Nauki i Szkolnictwa Wyższego z dnia 5 maja 2025 r."},
+		{dziennikUstaw, "Obwieszczenie Marszałka Sejmu Rzeczypospolitej Polskiej z dnia 12 listopada 2025 r.", "📢Obwieszczenie Marszałka Sejmu Rzeczypospolitej Polskiej z dnia 12 listopada 2025 r."},
+		{dziennikUstaw, "Obwieszczenie Marszałka Sejmu Rzeczypospolitej Polskiej z dnia 13 listopada 2025 r.", "📢Obwieszczenie @wlodekczarzasty z dnia 13 listopada 2025 r."},
+		{dziennikUstaw, "Rozporządzenie Ministra Klimatu i Środowiska z dnia 1 marca 2024 r.", "Rozporządzenie @MKiS_gov_PL z dnia 1 marca 2024 r."},
+		{dziennikUstaw, "Rozporządzenie Ministra Finansów, Funduszy i Polityki Regionalnej oraz Ministra Finansów", "Rozporządzenie @MF_gov_PL oraz @MF_gov_PL"},
+		{dziennikUstaw, "Komunikat Narodowego Banku Polskiego", "Komunikat Narodowego Banku Polskiego"},
+		{monitorPolski, "Komunikat Narodowego Banku Polskiego", "Komunikat @nbppl"},
+		{dziennikUstaw, "Obwieszczenie w sprawie informacji przekazywanych Centralnemu Biuru Antykorupcyjnemu", "📢Obwieszczenie w sprawie informacji przekazywanych @CBAgovPL"},
+		{dziennikUstaw, "Ustawa z dnia 9 czerwca 2006 r. o Centralnym Biurze Antykorupcyjnym", "Ustawa z dnia 9 czerwca 2006 r. o @CBAgovPL"},
+	}
+	for _, tt := range tests {
+		at := actDate(tt.title)
+		if got := d.addEmoji(tt.journal, at, d.twitterHandles(tt.journal, at, tt.title)); got != tt.want {
+			t.Errorf("%s %q = %q, want %q", tt.journal.Code, tt.title, got, tt.want)
+		}
+	}
+}
+
+func Test_dictionaryAuthorities(t *testing.T) {
+	t.Parallel()
+	d, err := loadDictionary(dictionaryFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		journal *journal
+		title   string
+		want    []string
+	}{
+		{dziennikUstaw, "Rozporządzenie Ministra Klimatu i Środowiska z dnia 1 marca 2024 r. w sprawie opłat", []string{"Ministra Klimatu i Środowiska"}},
+		{dziennikUstaw, "Rozporządzenie Ministra Finansów oraz Ministra Zdrowia z dnia 1 marca 2024 r. w sprawie Ministra Sportu", []string{"Ministra Finansów", "Ministra Zdrowia"}},
+		{dziennikUstaw, "Ustawa z dnia 9 czerwca 2006 r. o Centralnym Biurze Antykorupcyjnym", nil},
+		{dziennikUstaw, "ROZPORZĄDZENIE MINISTRA ZDROWIA z dnia 1 marca 2024 r.", []string{"Ministra Zdrowia"}},
+		{monitorPolski, "Komunikat Narodowego Banku Polskiego", []string{"Narodowego Banku Polskiego"}},
+	}
+	for _, tt := range tests {
+		if got := d.authorities(tt.journal, tt.title); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("authorities(%q) = %q, want %q", tt.title, got, tt.want)
+		}
+	}
+}
+
+func Test_dictionaryCanonical(t *testing.T) {
+	t.Parallel()
+	d, err := loadDictionary(dictionaryFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, want := range map[string]string{
+		"Minister Finansów":                    "Ministra Finansów",
+		"minister  finansów":                   "Ministra Finansów",
+		"Centralne Biuro Antykorupcyjne":       "Centralnego Biura Antykorupcyjnego",
+		"Ministra Finansów":                    "Ministra Finansów",
+		"Minister Finansów i Ministra Zdrowia": "",
+		"Sejm":                                 "",
+	} {
+		if got, _ := d.canonical(name); got != want {
+			t.Errorf("canonical(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func Test_dictionarySource(t *testing.T) {
+	t.Parallel()
+	path := filepath.Join(t.TempDir(), dictionaryFile)
+	s := &dictionarySource{path: path}
+	// a missing file falls back to the embedded dictionary
+	if d := s.get(); len(d.Institutions) == 0 {
+		t.Fatal("embedded dictionary is empty")
+	}
+
+	write := func(content string, modTime time.Time) {
+		t.Helper()
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(path, modTime, modTime); err != nil {
+			t.Fatal(err)
+		}
+	}
+	now := time.Now()
+	write(`{"version": 1, "institutions": [{"name": "Ministra Zdrowia", "twitter": "@MZ_GOV_PL"}]}`, now)
+	if got := s.get().twitterHandles(dziennikUstaw, now, "Ministra Zdrowia"); got != "@MZ_GOV_PL" {
+		t.Errorf("twitterHandles() = %q", got)
+	}
+	write(`{"version": 1, "institutions": [{"name": "Ministra Zdrowia", "twitter": "@MZ"}]}`, now.Add(time.Second))
+	if got := s.get().twitterHandles(dziennikUstaw, now, "Ministra Zdrowia"); got != "@MZ" {
+		t.Errorf("reloaded twitterHandles() = %q", got)
+	}
+	// an invalid file keeps the previous dictionary
+	write(`{"version": 1, "institutions": [{"name": "Ministra Zdrowia", "twitter": "MZ"}]}`, now.Add(2*time.Second))
+	if got := s.get().twitterHandles(dziennikUstaw, now, "Ministra Zdrowia"); got != "@MZ" {
+		t.Errorf("twitterHandles() after invalid file = %q", got)
+	}
+}
+
+func Test_dictionaryUsage(t *testing.T) {
+	t.Parallel()
+	d, err := parseDictionary([]byte(`{"version": 1, "institutions": [
+		{"name": "Ministra Klimatu", "twitter": "@MKiS_gov_PL"},
+		{"name": "Ministra Klimatu i Środowiska", "twitter": "@MKiS_gov_PL"},
+		{"name": "Ministra Zdrowia", "twitter": "@MZ_GOV_PL", "to": "2023-12-31"}
+	], "emojis": [{"prefix": "Obwieszczenie", "emoji": "📢"}, {"prefix": "Uchwała", "emoji": "🗳", "journals": ["MP"]}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	du, mp := openTestLedger(t), openTestLedger(t)
+	for _, e := range []ledgerEntry{
+		{Year: 2024, Pos: 1, Title: "Rozporządzenie Ministra Klimatu i Środowiska z dnia 2 stycznia 2024 r.", Status: statusPublished},
+		{Year: 2024, Pos: 2, Title: "Obwieszczenie Ministra Klimatu i Środowiska z dnia 3 stycznia 2024 r.", Status: statusPublished},
+		{Year: 2024, Pos: 3, Status: statusMissing},
+		{Year: 2023, Pos: 9, Title: "Rozporządzenie Ministra Zdrowia z dnia 2 stycznia 2023 r.", Status: statusPublished},
+	} {
+		if err := du.record(e); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := mp.record(ledgerEntry{Year: 2024, Pos: 5, Title: "Uchwała Rady Ministrów", Status: statusPublished}); err != nil {
+		t.Fatal(err)
+	}
+
+	var b strings.Builder
+	dictionaryUsage(&b, d, latestActs(map[*journal]*ledger{dziennikUstaw: du, monitorPolski: mp}, 3))
+	want := `Dictionary entries used in the last 3 acts
+
+Institutions:
+    2  Ministra Klimatu i Środowiska @MKiS_gov_PL, last in Dz.U. 2024 poz. 2
+    0  Ministra Klimatu @MKiS_gov_PL
+    0  Ministra Zdrowia @MZ_GOV_PL (to 2023-12-31)
+
+Emojis:
+    1  📢 Obwieszczenie, last in Dz.U. 2024 poz. 2
+    1  🗳 Uchwała (MP), last in M.P. 2024 poz. 5
+`
+	if got := b.String(); got != want {
+		t.Errorf("dictionaryUsage() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func openTestLedger(t *testing.T) *ledger {
+	t.Helper()
+	l, err := openLedger(filepath.Join(t.TempDir(), ledgerFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return l
+}
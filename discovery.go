@@ -0,0 +1,194 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// maxNewActs limits how many acts are published in a single run, MAX_NEW_ACTS overrides it.
+	maxNewActs = 3
+	// defaultDiscoveryWindow is how many consecutive missing positions end the scan.
+	defaultDiscoveryWindow = 5
+	// gapReportAge is how long a position may be missing before it is reported.
+	gapReportAge = 7 * 24 * time.Hour
+	// gapCheckAge is how long a missing position is checked, older gaps are most likely numbers never used.
+	gapCheckAge = 60 * 24 * time.Hour
+)
+
+// discovery finds newly published positions. Positions are not always
+// published in order so it scans ahead of the last published one and
+// remembers skipped positions to publish them once they appear.
+type discovery struct {
+	journal *journal
+	window  int
+	// limit is how many acts are published in a single run, maxNewActs when zero.
+	limit  int
+	gapAge time.Duration
+	// maxGapAge stops checking positions missing for longer, they are checked forever when zero.
+	maxGapAge time.Duration
+}
+
+func newDiscovery(j *journal) *discovery {
+	window := defaultDiscoveryWindow
+	if w, err := strconv.Atoi(os.Getenv("DISCOVERY_WINDOW")); err == nil && w > 0 {
+		window = w
+	}
+	limit := maxNewActs
+	if l, err := strconv.Atoi(os.Getenv("MAX_NEW_ACTS")); err == nil && l > 0 {
+		limit = l
+	}
+	return &discovery{journal: j, window: window, limit: limit, gapAge: gapReportAge, maxGapAge: gapCheckAge}
+}
+
+type discovered struct {
+	// Found are positions ready to publish, previously skipped ones first.
+	Found []actKey
+	// Meta are the parsed pages of the found positions so they are not downloaded again.
+	Meta map[actKey]Act
+	// Gaps are positions missing below the highest found one.
+	Gaps []actKey
+}
+
+func (d *discovered) add(k actKey, meta Act) {
+	if d.Meta == nil {
+		d.Meta = map[actKey]Act{}
+	}
+	d.Found = append(d.Found, k)
+	d.Meta[k] = meta
+}
+
+// discover finds at most limit positions to publish, the scan stops once they are found.
+func (d *discovery) discover(ctx context.Context, l *ledger, year int) (discovered, error) {
+	limit := d.limit
+	if limit == 0 {
+		limit = maxNewActs
+	}
+	var result discovered
+	for _, e := range l.missing() {
+		if len(result.Found) == limit {
+			return result, nil
+		}
+		logger := log.WithField("Year", e.Year).WithField("Pos", e.Pos)
+		if d.maxGapAge > 0 && time.Since(e.Created) > d.maxGapAge {
+			logger.WithField("Since", e.Created.Format(time.DateOnly)).Debug("Gap is not checked anymore")
+			continue
+		}
+		meta, err := d.journal.site.meta(ctx, e.Year, e.Pos)
+		if err != nil {
+			return discovered{}, err
+		}
+		if meta.Title != "" {
+			logger.Info("Skipped position was published")
+			result.add(e.key(), meta)
+			continue
+		}
+		if age := time.Since(e.Created); age > d.gapAge {
+			logger.WithField("Since", e.Created.Format(time.DateOnly)).Warn("Long-standing gap")
+		}
+	}
+
+	var gaps []actKey
+	misses := 0
+	for pos := l.last(year) + 1; misses < d.window && len(result.Found) < limit; pos++ {
+		meta, err := d.journal.site.meta(ctx, year, pos)
+		if err != nil {
+			return discovered{}, err
+		}
+		if meta.Title == "" {
+			misses++
+			gaps = append(gaps, actKey{Year: year, Pos: pos})
+			continue
+		}
+		result.add(actKey{Year: year, Pos: pos}, meta)
+		result.Gaps = append(result.Gaps, gaps...)
+		gaps, misses = nil, 0
+	}
+	return result, nil
+}
+
+// newest returns the highest published position of the year, 0 when nothing is published yet. Positions are
+// published mostly in order so it doubles the position until one is missing, bisects and scans ahead like discover.
+func (d *discovery) newest(ctx context.Context, year int) (int, error) {
+	exists := func(pos int) (bool, error) {
+		meta, err := d.journal.site.meta(ctx, year, pos)
+		return meta.Title != "", err
+	}
+	low, high := 0, 1
+	for {
+		ok, err := exists(high)
+		if err != nil {
+			return 0, err
+		}
+		if !ok {
+			break
+		}
+		low, high = high, high*2
+	}
+	for high-low > 1 {
+		mid := (low + high) / 2
+		ok, err := exists(mid)
+		if err != nil {
+			return 0, err
+		}
+		if ok {
+			low = mid
+		} else {
+			high = mid
+		}
+	}
+	for pos, misses := low+1, 0; misses < d.window; pos++ {
+		ok, err := exists(pos)
+		if err != nil {
+			return 0, err
+		}
+		if ok {
+			low, misses = pos, 0
+		} else {
+			misses++
+		}
+	}
+	return low, nil
+}
+
+// seed records the newest published act in an empty ledger so the first run publishes only acts
+// published from now on, not the whole year.
+func (d *discovery) seed(ctx context.Context, l *ledger) error {
+	year := time.Now().Year()
+	for _, y := range []int{year, year - 1} {
+		pos, err := d.newest(ctx, y)
+		if err != nil {
+			return err
+		}
+		if pos > 0 {
+			log.WithField("Year", y).WithField("Pos", pos).Infof("Seeding %s with the newest published act", l.path)
+			return l.record(ledgerEntry{Year: y, Pos: pos, Status: statusPublished})
+		}
+	}
+	return fmt.Errorf("no acts published in %d and %d", year-1, year)
+}
+
+// recordGaps remembers positions skipped below the highest published one.
+func recordGaps(l *ledger, gaps []actKey, acts []newAct) error {
+	highest := map[int]int{}
+	for _, act := range acts {
+		if act.Pos > highest[act.Year] {
+			highest[act.Year] = act.Pos
+		}
+	}
+	for _, gap := range gaps {
+		if _, ok := l.get(gap.Year, gap.Pos); ok || gap.Pos > highest[gap.Year] {
+			continue
+		}
+		log.WithField("Year", gap.Year).WithField("Pos", gap.Pos).Warn("Position skipped, it will be published once it appears")
+		if err := l.record(ledgerEntry{Year: gap.Year, Pos: gap.Pos, Status: statusMissing}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
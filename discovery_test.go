@@ -0,0 +1,241 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeSite serves act pages for published positions and the 404 page for the others, like dziennikustaw.gov.pl.
+type fakeSite struct {
+	mu        sync.Mutex
+	published map[actKey]string
+	// pages counts downloads of act pages.
+	pages map[actKey]int
+}
+
+func newFakeSite(t *testing.T, base *journal, published map[actKey]string) (*fakeSite, *journal) {
+	notFound, err := os.ReadFile("testdata/404.html")
+	if err != nil {
+		t.Fatal(err)
+	}
+	pdf, err := os.ReadFile("testdata/D2020000000101.pdf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := &fakeSite{published: published, pages: map[actKey]int{}}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /"+base.Code+"/{year}/{pos}", func(w http.ResponseWriter, r *http.Request) {
+		year, _ := strconv.Atoi(r.PathValue("year"))
+		pos, _ := strconv.Atoi(r.PathValue("pos"))
+		f.mu.Lock()
+		title, ok := f.published[actKey{year, pos}]
+		f.pages[actKey{year, pos}]++
+		f.mu.Unlock()
+		if !ok {
+			w.Write(notFound)
+			return
+		}
+		fmt.Fprintf(w, `<h2 class="one-item-heading">%s</h2><table><tr><td>Data ogłoszenia:</td><td>%d-01-02</td></tr></table>`, title, year)
+	})
+	mux.HandleFunc("GET /{file}", func(w http.ResponseWriter, r *http.Request) {
+		w.Write(pdf)
+	})
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	j := *base
+	j.site = &site{base: srv.URL, code: base.Code, client: srv.Client()}
+	// fetched texts and the ledger never go to the source tree
+	j.Texts, j.Ledger = t.TempDir(), filepath.Join(t.TempDir(), filepath.Base(base.Ledger))
+	return f, &j
+}
+
+func (f *fakeSite) publish(year, pos int, title string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.published[actKey{year, pos}] = title
+}
+
+func Test_discover(t *testing.T) {
+	t.Parallel()
+	l, err := openLedger(filepath.Join(t.TempDir(), ledgerFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := l.record(ledgerEntry{Year: 2026, Pos: 10, Status: statusPublished}); err != nil {
+		t.Fatal(err)
+	}
+	f, j := newFakeSite(t, dziennikUstaw, map[actKey]string{
+		{2026, 11}: "Ustawa 11",
+		{2026, 13}: "Ustawa 13",
+		{2026, 14}: "Ustawa 14",
+	})
+	d := &discovery{journal: j, window: 3, gapAge: time.Hour}
+
+	result, err := d.discover(context.Background(), l, 2026)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(result.Found) != "[{2026 11} {2026 13} {2026 14}]" || fmt.Sprint(result.Gaps) != "[{2026 12}]" {
+		t.Errorf("discover() = %+v", result)
+	}
+
+	acts := []newAct{{Year: 2026, Pos: 11}, {Year: 2026, Pos: 13}}
+	if err := recordGaps(l, result.Gaps, acts); err != nil {
+		t.Fatal(err)
+	}
+	for _, act := range acts {
+		if err := l.record(ledgerEntry{Year: act.Year, Pos: act.Pos, Status: statusPublished}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if e, _ := l.get(2026, 12); e.Status != statusMissing {
+		t.Errorf("gap = %+v", e)
+	}
+	if got := l.last(2026); got != 13 {
+		t.Errorf("last() = %d, want 13", got)
+	}
+
+	f.publish(2026, 12, "Ustawa 12")
+	result, err = d.discover(context.Background(), l, 2026)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(result.Found) != "[{2026 12} {2026 14}]" || len(result.Gaps) != 0 {
+		t.Errorf("discover() = %+v", result)
+	}
+}
+
+func Test_discoverOldGaps(t *testing.T) {
+	t.Parallel()
+	l, err := openLedger(filepath.Join(t.TempDir(), ledgerFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := l.record(ledgerEntry{Year: 2026, Pos: 10, Status: statusPublished}); err != nil {
+		t.Fatal(err)
+	}
+	old := time.Now().Add(-90 * 24 * time.Hour)
+	l.entries[actKey{2026, 3}] = ledgerEntry{Year: 2026, Pos: 3, Status: statusMissing, Created: old, Updated: old}
+	l.entries[actKey{2026, 7}] = ledgerEntry{Year: 2026, Pos: 7, Status: statusMissing, Created: time.Now(), Updated: time.Now()}
+	f, j := newFakeSite(t, dziennikUstaw, map[actKey]string{})
+	d := &discovery{journal: j, window: 1, gapAge: time.Hour, maxGapAge: 30 * 24 * time.Hour}
+	if _, err := d.discover(context.Background(), l, 2026); err != nil {
+		t.Fatal(err)
+	}
+	// the old gap is not checked anymore, the recent one is
+	if want := map[actKey]int{{2026, 7}: 1, {2026, 11}: 1}; !reflect.DeepEqual(f.pages, want) {
+		t.Errorf("pages = %v, want %v", f.pages, want)
+	}
+}
+
+func Test_recordGapsAboveLimit(t *testing.T) {
+	t.Parallel()
+	l, err := openLedger(filepath.Join(t.TempDir(), ledgerFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	gaps := []actKey{{2026, 2}, {2026, 5}}
+	if err := recordGaps(l, gaps, []newAct{{Year: 2026, Pos: 3}}); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := l.get(2026, 2); !ok {
+		t.Errorf("gap below published act should be recorded")
+	}
+	if _, ok := l.get(2026, 5); ok {
+		t.Errorf("gap above published acts should not be recorded")
+	}
+}
+
+func Test_prepareNewActs(t *testing.T) {
+	t.Parallel()
+	l, err := openLedger(filepath.Join(t.TempDir(), ledgerFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	year := time.Now().Year()
+	if err := l.record(ledgerEntry{Year: year - 1, Pos: 2000, Status: statusPublished}); err != nil {
+		t.Fatal(err)
+	}
+	published := map[actKey]string{}
+	for pos := 1; pos <= 5; pos++ {
+		published[actKey{year, pos}] = "Rozporządzenie Ministra Zdrowia z dnia 2 stycznia 2026 r. w sprawie " + strconv.Itoa(pos)
+	}
+	f, j := newFakeSite(t, dziennikUstaw, published)
+	acts, gaps, err := prepareNewActs(context.Background(), l, &discovery{journal: j, window: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(acts) != maxNewActs || len(gaps) != 0 {
+		t.Fatalf("prepareNewActs() = %d acts, gaps %v", len(acts), gaps)
+	}
+	if acts[0].Pos != 1 || acts[0].Meta.Authority != "Ministra Zdrowia" || len(acts[0].Pages) != 2 {
+		t.Errorf("act = %+v", acts[0])
+	}
+	// every page is downloaded once and the scan stops at the limit
+	if want := map[actKey]int{{year, 1}: 1, {year, 2}: 1, {year, 3}: 1}; !reflect.DeepEqual(f.pages, want) {
+		t.Errorf("pages = %v, want %v", f.pages, want)
+	}
+}
+
+func Test_discoverySeed(t *testing.T) {
+	t.Parallel()
+	year := time.Now().Year()
+	published := map[actKey]string{{year - 1, 1}: "Ustawa"}
+	for pos := 1; pos <= 40; pos++ {
+		published[actKey{year, pos}] = "Obwieszczenie " + strconv.Itoa(pos)
+	}
+	// published out of order after a gap
+	delete(published, actKey{year, 37})
+	published[actKey{year, 42}] = "Obwieszczenie 42"
+	f, mp := newFakeSite(t, monitorPolski, published)
+	l, err := mp.openLedger()
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := &discovery{journal: mp, window: 2}
+	if err := d.seed(context.Background(), l); err != nil {
+		t.Fatal(err)
+	}
+	if y, pos := l.latest(); y != year || pos != 42 {
+		t.Errorf("latest() = %d %d, want %d 42", y, pos, year)
+	}
+	if len(f.pages) > 20 {
+		t.Errorf("seed() downloaded %d pages", len(f.pages))
+	}
+
+	// nothing is published yet in January, a dry run seeds the ledger in memory only
+	_, mp = newFakeSite(t, monitorPolski, map[actKey]string{{year - 1, 1}: "Ustawa", {year - 1, 2}: "Ustawa"})
+	l, err = mp.openLedger()
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.dry = true
+	if err := (&discovery{journal: mp, window: 2}).seed(context.Background(), l); err != nil {
+		t.Fatal(err)
+	}
+	if y, pos := l.latest(); y != year-1 || pos != 2 {
+		t.Errorf("latest() = %d %d, want %d 2", y, pos, year-1)
+	}
+	if _, err := os.Stat(mp.Ledger); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("dry run wrote the ledger: %v", err)
+	}
+
+	_, mp = newFakeSite(t, monitorPolski, map[actKey]string{})
+	l, err = mp.openLedger()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := (&discovery{journal: mp, window: 2}).seed(context.Background(), l); err == nil {
+		t.Error("seed() without published acts returned no error")
+	}
+}
@@ -0,0 +1,187 @@
+package main
+
+import (
+	"encoding/xml"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+const (
+	feedDir     = "feed"
+	feedEntries = 50
+)
+
+type atomFeed struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	Title   string      `xml:"title"`
+	ID      string      `xml:"id"`
+	Updated string      `xml:"updated"`
+	Links   []atomLink  `xml:"link"`
+	Author  atomAuthor  `xml:"author"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr,omitempty"`
+	Type string `xml:"type,attr,omitempty"`
+}
+
+type atomAuthor struct {
+	Name string `xml:"name"`
+}
+
+type atomText struct {
+	Type string `xml:"type,attr"`
+	Body string `xml:",chardata"`
+}
+
+type atomEntry struct {
+	Title     string     `xml:"title"`
+	ID        string     `xml:"id"`
+	Links     []atomLink `xml:"link"`
+	Published string     `xml:"published"`
+	Updated   string     `xml:"updated"`
+	Content   *atomText  `xml:"content,omitempty"`
+}
+
+type rssFeed struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	Channel rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title         string    `xml:"title"`
+	Link          string    `xml:"link"`
+	Description   string    `xml:"description"`
+	Language      string    `xml:"language"`
+	LastBuildDate string    `xml:"lastBuildDate"`
+	Items         []rssItem `xml:"item"`
+}
+
+type rssItem struct {
+	Title       string  `xml:"title"`
+	Link        string  `xml:"link"`
+	Description string  `xml:"description,omitempty"`
+	GUID        rssGUID `xml:"guid"`
+	PubDate     string  `xml:"pubDate"`
+}
+
+type rssGUID struct {
+	IsPermaLink bool   `xml:"isPermaLink,attr"`
+	ID          string `xml:",chardata"`
+}
+
+// feedItems returns the most recently discovered acts with known titles, newest first.
+func feedItems(l *ledger, limit int) []ledgerEntry {
+	var items []ledgerEntry
+	for _, e := range l.entries {
+		if e.Title != "" {
+			items = append(items, e)
+		}
+	}
+	sort.Slice(items, func(i, j int) bool {
+		if items[i].Year != items[j].Year {
+			return items[i].Year > items[j].Year
+		}
+		return items[i].Pos > items[j].Pos
+	})
+	if len(items) > limit {
+		items = items[:limit]
+	}
+	return items
+}
+
+// announced returns the announcement date of the act or when it was discovered if the date is unknown.
+func (e ledgerEntry) announced() time.Time {
+	if e.Act != nil && e.Act.Announced != nil {
+		return e.Act.Announced.Time
+	}
+	return e.Created
+}
+
+// buildAtomFeed uses act page URLs as entry IDs, they do not change when the act is republished.
+func buildAtomFeed(j *journal, items []ledgerEntry) atomFeed {
+	feed := atomFeed{
+		Title:  j.Name,
+		ID:     j.site.base + "/",
+		Author: atomAuthor{Name: j.Name},
+		Links: []atomLink{
+			{Href: j.site.base, Rel: "alternate"},
+		},
+	}
+	var updated time.Time
+	for _, e := range items {
+		if e.Updated.After(updated) {
+			updated = e.Updated
+		}
+		entry := atomEntry{
+			Title: j.header(e.Year, e.Pos) + " " + e.Title,
+			ID:    j.actPageUrl(e.Year, e.Pos),
+			Links: []atomLink{
+				{Href: j.actPageUrl(e.Year, e.Pos), Rel: "alternate", Type: "text/html"},
+				{Href: j.pdfUrl(e.Year, e.Nr, e.Pos), Rel: "enclosure", Type: "application/pdf"},
+			},
+			Published: e.announced().Format(time.RFC3339),
+			Updated:   e.Updated.Format(time.RFC3339),
+		}
+		if e.Summary != "" {
+			entry.Content = &atomText{Type: "text", Body: e.Summary}
+		}
+		feed.Entries = append(feed.Entries, entry)
+	}
+	feed.Updated = updated.Format(time.RFC3339)
+	return feed
+}
+
+func buildRSSFeed(j *journal, items []ledgerEntry) rssFeed {
+	channel := rssChannel{
+		Title:       j.Name,
+		Link:        j.site.base,
+		Description: j.About,
+		Language:    "pl",
+	}
+	var updated time.Time
+	for _, e := range items {
+		if e.Updated.After(updated) {
+			updated = e.Updated
+		}
+		channel.Items = append(channel.Items, rssItem{
+			Title:       j.header(e.Year, e.Pos) + " " + e.Title,
+			Link:        j.pdfUrl(e.Year, e.Nr, e.Pos),
+			Description: e.Summary,
+			GUID:        rssGUID{ID: j.actPageUrl(e.Year, e.Pos)},
+			PubDate:     e.announced().Format(time.RFC1123Z),
+		})
+	}
+	channel.LastBuildDate = updated.Format(time.RFC1123Z)
+	return rssFeed{Version: "2.0", Channel: channel}
+}
+
+// writeFeeds regenerates Atom and RSS feeds of the ledger journal.
+func writeFeeds(l *ledger, dir string) error {
+	items := feedItems(l, feedEntries)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	if err := writeXML(filepath.Join(dir, "atom.xml"), buildAtomFeed(l.journal, items)); err != nil {
+		return err
+	}
+	return writeXML(filepath.Join(dir, "rss.xml"), buildRSSFeed(l.journal, items))
+}
+
+// writeXML writes the document to a temporary file first so readers never see a partial feed.
+func writeXML(path string, v any) error {
+	data, err := xml.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, append([]byte(xml.Header), append(data, '\n')...), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
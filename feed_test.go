@@ -0,0 +1,79 @@
+package main
+
+import (
+	"encoding/xml"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func Test_writeFeeds(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	l, err := openLedger(filepath.Join(dir, ledgerFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range []ledgerEntry{
+		{Year: 2025, Pos: 2000, Status: statusPublished},
+		{Year: 2025, Pos: 2001, Title: "Ustawa o zmianie ustawy", Summary: "Ważna zmiana & <nowość>", Status: statusPublished},
+		{Year: 2026, Pos: 1, Title: "Rozporządzenie Ministra Zdrowia", Status: statusPosted},
+	} {
+		if err := l.record(e); err != nil {
+			t.Fatal(err)
+		}
+	}
+	out := filepath.Join(dir, feedDir)
+	if err := writeFeeds(l, out); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(out, "atom.xml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var atom atomFeed
+	if err := xml.Unmarshal(data, &atom); err != nil {
+		t.Fatal(err)
+	}
+	if len(atom.Entries) != 2 {
+		t.Fatalf("expected 2 entries got %d", len(atom.Entries))
+	}
+	if atom.Entries[0].ID != "https://dziennikustaw.gov.pl/DU/2026/1" || atom.Entries[0].Content != nil {
+		t.Errorf("first entry = %+v", atom.Entries[0])
+	}
+	second := atom.Entries[1]
+	if second.Title != "Dz.U. 2025 poz. 2001 Ustawa o zmianie ustawy" || second.Content.Body != "Ważna zmiana & <nowość>" {
+		t.Errorf("second entry = %+v", second)
+	}
+	if second.Links[1].Href != "https://dziennikustaw.gov.pl/D2025000200101.pdf" {
+		t.Errorf("links = %+v", second.Links)
+	}
+
+	data, err = os.ReadFile(filepath.Join(out, "rss.xml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var rss rssFeed
+	if err := xml.Unmarshal(data, &rss); err != nil {
+		t.Fatal(err)
+	}
+	if rss.Version != "2.0" || len(rss.Channel.Items) != 2 {
+		t.Fatalf("rss = %+v", rss)
+	}
+	if item := rss.Channel.Items[1]; item.GUID.ID != "https://dziennikustaw.gov.pl/DU/2025/2001" || item.GUID.IsPermaLink || item.Description != "Ważna zmiana & <nowość>" {
+		t.Errorf("item = %+v", item)
+	}
+}
+
+func Test_feedItemsLimit(t *testing.T) {
+	t.Parallel()
+	l := &ledger{entries: map[actKey]ledgerEntry{}}
+	for pos := 1; pos <= 10; pos++ {
+		l.entries[actKey{2026, pos}] = ledgerEntry{Year: 2026, Pos: pos, Title: "Ustawa"}
+	}
+	items := feedItems(l, 3)
+	if len(items) != 3 || items[0].Pos != 10 || items[2].Pos != 8 {
+		t.Errorf("feedItems() = %+v", items)
+	}
+}
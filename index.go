@@ -0,0 +1,291 @@
+package main
+
+import (
+	"encoding/gob"
+	"errors"
+	"flag"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	indexFile = "index.gob"
+	// titleWeight makes words from the title count more than words from the act text.
+	titleWeight = 3
+)
+
+// searchIndex is an inverted index of act titles, summaries and texts persisted between runs.
+// Documents are identified by ELI and reindexed when their ledger entry changes.
+type searchIndex struct {
+	path string
+	mu   sync.Mutex
+
+	Docs map[string]indexedDoc
+	// Terms maps a stem to frequencies in documents containing it.
+	Terms map[string]map[string]int
+}
+
+type indexedDoc struct {
+	// Version is the update time of the ledger entry when the act was indexed.
+	Version time.Time
+	Length  int
+	Terms   []string
+}
+
+type searchHit struct {
+	ELI   string
+	Score float64
+}
+
+func openSearchIndex(path string) (*searchIndex, error) {
+	idx := &searchIndex{path: path, Docs: map[string]indexedDoc{}, Terms: map[string]map[string]int{}}
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return idx, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	if err := gob.NewDecoder(f).Decode(idx); err != nil {
+		return nil, fmt.Errorf("could not read index %s: %w", path, err)
+	}
+	return idx, nil
+}
+
+// save writes the index to a temporary file first so readers never see a partially written index.
+func (idx *searchIndex) save() error {
+	if dir := filepath.Dir(idx.path); dir != "." {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return err
+		}
+	}
+	tmp := idx.path + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	if err := gob.NewEncoder(f).Encode(idx); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp, idx.path)
+}
+
+// refresh indexes acts added or changed in the ledgers since the last refresh and saves the index.
+func (idx *searchIndex) refresh(ledgers []*ledger) error {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	changed := 0
+	for _, l := range ledgers {
+		for _, e := range l.entries {
+			if e.Status == statusMissing {
+				continue
+			}
+			eli := citation{Journal: l.journal, Year: e.Year, Pos: e.Pos}.eli()
+			if doc, ok := idx.Docs[eli]; ok && doc.Version.Equal(e.Updated) {
+				continue
+			}
+			text, err := l.journal.loadText(e.Year, e.Pos)
+			if err != nil {
+				return err
+			}
+			idx.add(eli, e.Updated, e.Title, e.Summary+"\n"+text)
+			changed++
+		}
+	}
+	if changed == 0 {
+		return nil
+	}
+	log.WithField("Acts", changed).Debug("Indexed acts")
+	return idx.save()
+}
+
+func (idx *searchIndex) add(eli string, version time.Time, title, text string) {
+	idx.remove(eli)
+	freqs := map[string]int{}
+	for _, term := range analyze(title) {
+		freqs[term] += titleWeight
+	}
+	for _, term := range analyze(text) {
+		freqs[term]++
+	}
+	doc := indexedDoc{Version: version}
+	for term, n := range freqs {
+		if idx.Terms[term] == nil {
+			idx.Terms[term] = map[string]int{}
+		}
+		idx.Terms[term][eli] = n
+		doc.Terms = append(doc.Terms, term)
+		doc.Length += n
+	}
+	idx.Docs[eli] = doc
+}
+
+func (idx *searchIndex) remove(eli string) {
+	for _, term := range idx.Docs[eli].Terms {
+		delete(idx.Terms[term], eli)
+		if len(idx.Terms[term]) == 0 {
+			delete(idx.Terms, term)
+		}
+	}
+	delete(idx.Docs, eli)
+}
+
+// search returns acts containing all words of the query ranked with BM25.
+func (idx *searchIndex) search(query string) []searchHit {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	terms := analyze(query)
+	if len(terms) == 0 || len(idx.Docs) == 0 {
+		return nil
+	}
+	const k1, b = 1.2, 0.75
+	total := 0
+	for _, doc := range idx.Docs {
+		total += doc.Length
+	}
+	avgLength := float64(total) / float64(len(idx.Docs))
+
+	scores := map[string]float64{}
+	for eli := range idx.Terms[terms[0]] {
+		scores[eli] = 0
+	}
+	for _, term := range terms {
+		postings := idx.Terms[term]
+		idf := math.Log(1 + (float64(len(idx.Docs))-float64(len(postings))+0.5)/(float64(len(postings))+0.5))
+		for eli := range scores {
+			n, ok := postings[eli]
+			if !ok {
+				delete(scores, eli)
+				continue
+			}
+			tf := float64(n)
+			norm := k1 * (1 - b + b*float64(idx.Docs[eli].Length)/avgLength)
+			scores[eli] += idf * tf * (k1 + 1) / (tf + norm)
+		}
+	}
+
+	hits := make([]searchHit, 0, len(scores))
+	for eli, score := range scores {
+		hits = append(hits, searchHit{ELI: eli, Score: score})
+	}
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].Score != hits[j].Score {
+			return hits[i].Score > hits[j].Score
+		}
+		return hits[i].ELI < hits[j].ELI
+	})
+	return hits
+}
+
+// polishStopWords are too common to be searched for.
+var polishStopWords = map[string]bool{
+	"a": true, "i": true, "o": true, "u": true, "w": true, "z": true, "we": true, "ze": true, "na": true, "do": true,
+	"od": true, "po": true, "za": true, "dla": true, "lub": true, "oraz": true, "sie": true, "jest": true,
+	"nr": true, "poz": true, "r": true, "dnia": true, "sprawie": true,
+}
+
+// polishSuffixes are inflectional endings removed by stem, longer endings first. Endings are without diacritics.
+var polishSuffixes = []string{
+	"owie", "ami", "ach", "owi", "iem", "ego", "emu", "ymi", "imi", "ych", "ich",
+	"om", "ow", "ie", "ej", "ym", "im", "ia", "iu", "ii",
+	"a", "e", "i", "o", "u", "y",
+}
+
+// analyze splits the text into searchable terms: lowercase words without diacritics and inflectional endings.
+func analyze(text string) []string {
+	var terms []string
+	for _, word := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		word = foldDiacritics(word)
+		if polishStopWords[word] {
+			continue
+		}
+		terms = append(terms, stem(word))
+	}
+	return terms
+}
+
+var diacritics = strings.NewReplacer("ą", "a", "ć", "c", "ę", "e", "ł", "l", "ń", "n", "ó", "o", "ś", "s", "ź", "z", "ż", "z")
+
+func foldDiacritics(word string) string {
+	return diacritics.Replace(word)
+}
+
+// stem is a light Polish stemmer, it strips a single inflectional ending so "podatek", "podatku"
+// and "podatków" share the stem "podatk". Short words and numbers are left as they are.
+func stem(word string) string {
+	if len(word) < 5 || unicode.IsDigit(rune(word[0])) {
+		return word
+	}
+	for _, suffix := range polishSuffixes {
+		if strings.HasSuffix(word, suffix) && len(word)-len(suffix) >= 4 {
+			word = strings.TrimSuffix(word, suffix)
+			break
+		}
+	}
+	// the vowel in "-ek" disappears in other forms: podatek, podatku
+	if strings.HasSuffix(word, "ek") && len(word) > 5 {
+		word = word[:len(word)-2] + "k"
+	}
+	return word
+}
+
+func searchCommand(args []string) {
+	fs := flag.NewFlagSet("search", flag.ExitOnError)
+	limit := fs.Int("limit", 20, "maximum number of results")
+	fs.Parse(args)
+	query := strings.Join(fs.Args(), " ")
+	if query == "" {
+		fmt.Fprintln(os.Stderr, "missing query")
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	var ledgers []*ledger
+	for _, j := range journals {
+		l, err := j.openLedger()
+		if err != nil {
+			log.WithError(err).Fatal("Could not open ledger")
+		}
+		ledgers = append(ledgers, l)
+	}
+	idx, err := openSearchIndex(indexFile)
+	if err != nil {
+		log.WithError(err).Fatal("Could not open index")
+	}
+	if err := idx.refresh(ledgers); err != nil {
+		log.WithError(err).Fatal("Could not update index")
+	}
+	for i, hit := range idx.search(query) {
+		if i == *limit {
+			break
+		}
+		c, err := parseELI(hit.ELI)
+		if err != nil {
+			continue
+		}
+		title := ""
+		for _, l := range ledgers {
+			if l.journal == c.Journal {
+				e, _ := l.get(c.Year, c.Pos)
+				title = e.Title
+			}
+		}
+		fmt.Printf("%s %s\n", c, title)
+	}
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func Test_analyze(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		text string
+		want string
+	}{
+		{text: "podatek od nieruchomości", want: "podatk nieruchomosc"},
+		{text: "Podatku od Nieruchomosci", want: "podatk nieruchomosc"},
+		{text: "podatków", want: "podatk"},
+		{text: "ustawa, ustawy, ustawie, ustawą", want: "ustaw ustaw ustaw ustaw"},
+		{text: "pomoc de minimis", want: "pomoc de minimis"},
+		{text: "Dz.U. 2020 poz. 1", want: "dz 2020 1"},
+		{text: "opodatkowanie opodatkowania", want: "opodatkowan opodatkowan"},
+	}
+	for _, tt := range tests {
+		if got := strings.Join(analyze(tt.text), " "); got != tt.want {
+			t.Errorf("analyze(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func Test_searchIndex(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	du := *dziennikUstaw
+	du.Ledger, du.Texts = filepath.Join(dir, ledgerFile), filepath.Join(dir, "text")
+	l, err := du.openLedger()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range []ledgerEntry{
+		{Year: 2020, Pos: 1, Title: "Ustawa o podatkach i opłatach lokalnych", Status: statusArchived},
+		{Year: 2020, Pos: 2, Title: "Rozporządzenie w sprawie pomocy", Summary: "Pomoc de minimis dla rolników", Status: statusArchived},
+		{Year: 2020, Pos: 3, Title: "Obwieszczenie", Status: statusArchived},
+	} {
+		if err := l.record(e); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := du.saveText(2020, 1, "Art. 1. Rada gminy określa stawki podatku od nieruchomości."); err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(dir, indexFile)
+	idx, err := openSearchIndex(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := idx.refresh([]*ledger{l}); err != nil {
+		t.Fatal(err)
+	}
+	search := func(idx *searchIndex, query string) string {
+		var elis []string
+		for _, hit := range idx.search(query) {
+			elis = append(elis, hit.ELI)
+		}
+		return fmt.Sprint(elis)
+	}
+	for query, want := range map[string]string{
+		"podatek od nieruchomości": "[DU/2020/1]",
+		"de minimis":               "[DU/2020/2]",
+		"pomoc":                    "[DU/2020/2]",
+		"podatek minimis":          "[]",
+		"od":                       "[]",
+	} {
+		if got := search(idx, query); got != want {
+			t.Errorf("search(%q) = %s, want %s", query, got, want)
+		}
+	}
+
+	// the index is persisted and changed acts are reindexed
+	if err := l.record(ledgerEntry{Year: 2020, Pos: 3, Title: "Obwieszczenie", Summary: "Tekst jednolity ustawy o podatku od nieruchomości", Status: statusArchived}); err != nil {
+		t.Fatal(err)
+	}
+	idx, err = openSearchIndex(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := search(idx, "nieruchomości"); got != "[DU/2020/1]" {
+		t.Errorf("persisted search = %s", got)
+	}
+	if err := idx.refresh([]*ledger{l}); err != nil {
+		t.Fatal(err)
+	}
+	if got := search(idx, "nieruchomości"); got != "[DU/2020/1 DU/2020/3]" && got != "[DU/2020/3 DU/2020/1]" {
+		t.Errorf("search after refresh = %s", got)
+	}
+	if len(idx.Docs) != 3 {
+		t.Errorf("indexed %d acts, want 3", len(idx.Docs))
+	}
+}
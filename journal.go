@@ -0,0 +1,189 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const monitorPolskiUrl = "https://monitorpolski.gov.pl"
+
+// journal is an official journal publishing legal acts. Every journal has its
+// own numbering so it keeps its own ledger, cursor and feeds.
+type journal struct {
+	// Code is the journal symbol used in act page URLs, e.g. DU in https://dziennikustaw.gov.pl/DU/2026/1.
+	Code string
+	Name string
+	// Prefix is the abbreviation used in citations, e.g. "Dz.U." in "Dz.U. 2026 poz. 1".
+	Prefix string
+	// About describes the feeds.
+	About  string
+	Ledger string
+	// Cursor is the legacy file holding the last published act, used to seed an empty ledger.
+	Cursor string
+	Feed   string
+	// Texts is the directory with extracted act texts used by the search index, relative to
+	// the texts root set with TEXTS.
+	Texts string
+	site  *site
+}
+
+var dziennikUstaw = &journal{
+	Code:   "DU",
+	Name:   "Dziennik Ustaw",
+	Prefix: "Dz.U.",
+	About:  "Nowe akty prawne opublikowane w Dzienniku Ustaw",
+	Ledger: ledgerFile,
+	Cursor: "last.txt",
+	Feed:   feedDir,
+	site:   dzu,
+}
+
+var monitorPolski = &journal{
+	Code:   "MP",
+	Name:   "Monitor Polski",
+	Prefix: "M.P.",
+	About:  "Nowe akty prawne opublikowane w Monitorze Polskim",
+	Ledger: "ledger-mp.jsonl",
+	Cursor: "last-mp.txt",
+	Feed:   feedDir + "/mp",
+	Texts:  "mp",
+	site:   &site{base: monitorPolskiUrl, code: "MP", client: client},
+}
+
+var journals = []*journal{dziennikUstaw, monitorPolski}
+
+func journalByCode(code string) (*journal, error) {
+	for _, j := range journals {
+		if strings.EqualFold(j.Code, code) {
+			return j, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown journal %q", code)
+}
+
+// enabledJournals returns journals listed in JOURNALS (e.g. "DU,MP"), only Dziennik Ustaw by default.
+func enabledJournals() ([]*journal, error) {
+	codes := os.Getenv("JOURNALS")
+	if codes == "" {
+		return []*journal{dziennikUstaw}, nil
+	}
+	var result []*journal
+	for _, code := range strings.Split(codes, ",") {
+		j, err := journalByCode(strings.TrimSpace(code))
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, j)
+	}
+	return result, nil
+}
+
+func (j *journal) openLedger() (*ledger, error) {
+	l, err := openLedger(j.Ledger)
+	if err != nil {
+		return nil, err
+	}
+	l.journal = j
+	return l, nil
+}
+
+// header identifies the act, e.g. "Dz.U. 2026 poz. 1".
+func (j *journal) header(year, pos int) string {
+	return fmt.Sprintf("%s %d poz. %d", j.Prefix, year, pos)
+}
+
+func (j *journal) actPageUrl(year, pos int) string {
+	return j.site.actPageUrl(year, pos)
+}
+
+func (j *journal) pdfUrl(year, nr, pos int) string {
+	return j.site.pdfUrl(year, nr, pos)
+}
+
+func (j *journal) fetchAct(ctx context.Context, year, pos int) (newAct, bool, error) {
+	return j.fetchParsedAct(ctx, year, pos, nil)
+}
+
+// fetchParsedAct fetches the act whose page was already parsed, e.g. during discovery, the page is
+// downloaded when meta is nil.
+func (j *journal) fetchParsedAct(ctx context.Context, year, pos int, meta *Act) (newAct, bool, error) {
+	act, found, err := j.site.fetchAct(ctx, year, pos, meta)
+	act.Journal = j
+	if err == nil && found {
+		act.Meta.Institutions = dictionaries.get().authorities(j, act.Title)
+	}
+	if err == nil && found && act.Text != "" {
+		if err := j.saveText(year, pos, act.Text); err != nil {
+			log.WithError(err).WithField("Year", year).WithField("Pos", pos).Warn("Could not save act text")
+		}
+	}
+	return act, found, err
+}
+
+// textDir returns the directory with the journal texts, TEXTS or defaultTextDir.
+func (j *journal) textDir() string {
+	if filepath.IsAbs(j.Texts) {
+		return j.Texts
+	}
+	root := os.Getenv("TEXTS")
+	if root == "" {
+		root = defaultTextDir()
+	}
+	return filepath.Join(root, j.Texts)
+}
+
+// defaultTextDir is in the user cache directory, texts are too big to be kept with the ledgers in the
+// repository the bot commits.
+func defaultTextDir() string {
+	cache, err := os.UserCacheDir()
+	if err != nil {
+		cache = os.TempDir()
+	}
+	return filepath.Join(cache, "du", "text")
+}
+
+func (j *journal) textPath(year, pos int) string {
+	return filepath.Join(j.textDir(), strconv.Itoa(year), strconv.Itoa(pos)+".txt")
+}
+
+func (j *journal) saveText(year, pos int, text string) error {
+	path := j.textPath(year, pos)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, []byte(text), 0o644)
+}
+
+// loadText returns the text of the act, it is empty for acts fetched before texts were saved.
+func (j *journal) loadText(year, pos int) (string, error) {
+	text, err := os.ReadFile(j.textPath(year, pos))
+	if errors.Is(err, os.ErrNotExist) {
+		return "", nil
+	}
+	return string(text), err
+}
+
+func (j *journal) prepareTweet(year, nr, id int, title string) string {
+	return strings.Join([]string{
+		j.header(year, id),     // 22 chars (Dz.U. YYYY poz. XXXX\n)
+		j.trimTitle(title),     // < 280-22-23 ~ 230 (1 for new line)
+		j.pdfUrl(year, nr, id), // 23 chars (The current length of a URL in a Tweet is 23 characters, even if the length of the URL would normally be shorter.)
+	}, "\n")
+}
+
+// trimTitle replaces institutions with their handles, adds the emoji and shortens the title to fit in a tweet.
+func (j *journal) trimTitle(title string) string {
+	d, at := dictionaries.get(), actDate(title)
+	return shortenTitle(d.addEmoji(j, at, d.twitterHandles(j, at, title)), MaxTitleLength, tweetLength)
+}
+
+func (j *journal) addEmoji(title string) string {
+	return dictionaries.get().addEmoji(j, actDate(title), title)
+}
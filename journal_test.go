@@ -0,0 +1,122 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func Test_journalPrepareTweet(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		journal *journal
+		title   string
+		want    string
+	}{
+		{
+			journal: dziennikUstaw,
+			title:   "Obwieszczenie Ministra Zdrowia z dnia 21 maja 2020 r.",
+			want:    "Dz.U. 2026 poz. 123\n📢Obwieszczenie @MZ_GOV_PL z dnia 21 maja 2020 r.\nhttps://dziennikustaw.gov.pl/D2026000012301.pdf",
+		},
+		{
+			journal: monitorPolski,
+			title:   "Obwieszczenie Prezesa Głównego Urzędu Statystycznego z dnia 13 października 2026 r. w sprawie wskaźnika cen",
+			want:    "M.P. 2026 poz. 123\n📢Obwieszczenie Prezesa @GUS_STAT z dnia 13 października 2026 r. w sprawie wskaźnika cen\nhttps://monitorpolski.gov.pl/M2026000012301.pdf",
+		},
+		{
+			journal: monitorPolski,
+			title:   "Uchwała nr 12 Rady Ministrów z dnia 2 lutego 2026 r.",
+			want:    "M.P. 2026 poz. 123\n🗳Uchwała nr 12 Rady Ministrów z dnia 2 lutego 2026 r.\nhttps://monitorpolski.gov.pl/M2026000012301.pdf",
+		},
+		{
+			journal: dziennikUstaw,
+			title:   "Uchwała nr 12 Rady Ministrów z dnia 2 lutego 2026 r.",
+			want:    "Dz.U. 2026 poz. 123\nUchwała nr 12 Rady Ministrów z dnia 2 lutego 2026 r.\nhttps://dziennikustaw.gov.pl/D2026000012301.pdf",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.journal.Code+" "+tt.title, func(t *testing.T) {
+			t.Parallel()
+			if got := tt.journal.prepareTweet(2026, 0, 123, tt.title); got != tt.want {
+				t.Errorf("prepareTweet() =\n%v, want\n%v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_enabledJournals(t *testing.T) {
+	t.Setenv("JOURNALS", "")
+	if got, err := enabledJournals(); err != nil || len(got) != 1 || got[0] != dziennikUstaw {
+		t.Errorf("enabledJournals() = %v, %v", got, err)
+	}
+	t.Setenv("JOURNALS", "du, mp")
+	if got, err := enabledJournals(); err != nil || len(got) != 2 || got[1] != monitorPolski {
+		t.Errorf("enabledJournals() = %v, %v", got, err)
+	}
+	t.Setenv("JOURNALS", "DU,OJ")
+	if _, err := enabledJournals(); err == nil {
+		t.Errorf("expected error for unknown journal")
+	}
+}
+
+func Test_monitorPolskiCursor(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	year := time.Now().Year()
+	_, mp := newFakeSite(t, monitorPolski, map[actKey]string{
+		{year, 41}: "Obwieszczenie Marszałka Sejmu Rzeczypospolitej Polskiej z dnia 2 stycznia 2026 r.",
+	})
+	l, err := mp.openLedger()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Dz.U. acts are in a separate ledger so they do not move the M.P. cursor.
+	du, err := openLedger(filepath.Join(dir, ledgerFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := du.record(ledgerEntry{Year: year, Pos: 100, Status: statusPublished}); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.record(ledgerEntry{Year: year, Pos: 40, Status: statusPublished}); err != nil {
+		t.Fatal(err)
+	}
+
+	acts, _, err := prepareNewActs(context.Background(), l, &discovery{journal: mp, window: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(acts) != 1 || acts[0].Pos != 41 || acts[0].journal() != mp {
+		t.Fatalf("prepareNewActs() = %+v", acts)
+	}
+	if got := prepareMastodonStatus(mp, year, acts[0].Nr, acts[0].Pos, acts[0].Title); got != fmt.Sprintf("M.P. %d poz. 41\n📢Obwieszczenie Marszałka Sejmu Rzeczypospolitej Polskiej z dnia 2 stycznia 2026 r.\n%s/M%d000004101.pdf", year, mp.site.base, year) {
+		t.Errorf("prepareMastodonStatus() = %s", got)
+	}
+
+	acts[0].Summary = func() (string, error) { return "Podsumowanie", nil }
+	fake := &fakePublisher{name: "fake"}
+	publishAct(context.Background(), l, acts[0], []publisher{fake})
+	if got := l.last(year); got != 41 {
+		t.Errorf("last() = %d, want 41", got)
+	}
+	if got := du.last(year); got != 100 {
+		t.Errorf("Dz.U. last() = %d, want 100", got)
+	}
+}
+
+func Test_journalTextDir(t *testing.T) {
+	mp := *monitorPolski
+	if got := mp.textPath(2026, 41); got != filepath.Join(defaultTextDir(), "mp", "2026", "41.txt") {
+		t.Errorf("textPath() = %s", got)
+	}
+	t.Setenv("TEXTS", "/var/lib/du/text")
+	if got := dziennikUstaw.textPath(2026, 1); got != filepath.Join("/var/lib/du/text", "2026", "1.txt") {
+		t.Errorf("textPath() with TEXTS = %s", got)
+	}
+	mp.Texts = "/tmp/mp"
+	if got := mp.textDir(); got != "/tmp/mp" {
+		t.Errorf("textDir() = %s, absolute directories are used as they are", got)
+	}
+}
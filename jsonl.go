@@ -0,0 +1,79 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// readJSONL decodes every line of an append-only JSONL file. A crash during
+// write can only break the last line so it is dropped, a broken line in the
+// middle of the file is corruption. A missing file is empty.
+func readJSONL[T any](path string, fn func(v T, line int) error) error {
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	var torn error
+	var offset int64
+	for n := 1; scanner.Scan(); n++ {
+		if torn != nil {
+			return torn
+		}
+		line := scanner.Bytes()
+		start := offset
+		offset += int64(len(line)) + 1
+		if len(line) == 0 {
+			continue
+		}
+		var v T
+		if err := json.Unmarshal(line, &v); err != nil {
+			torn = fmt.Errorf("%s:%d: %w", path, n, err)
+			offset = start
+			continue
+		}
+		if err := fn(v, n); err != nil {
+			return fmt.Errorf("%s:%d: %w", path, n, err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	if torn != nil {
+		log.WithError(torn).Warn("Dropping torn line")
+		return os.Truncate(path, offset)
+	}
+	return nil
+}
+
+// appendJSONL appends v as a single line and syncs the file so it survives a crash.
+func appendJSONL(path string, v any) error {
+	line, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(append(line, '\n')); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
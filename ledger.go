@@ -0,0 +1,260 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const ledgerFile = "ledger.jsonl"
+
+type actStatus string
+
+const (
+	// statusPending means the act was not announced yet, publishing will be retried.
+	statusPending actStatus = "pending"
+	// statusPosted means the act announcement is published but its summary reply is not.
+	statusPosted actStatus = "posted"
+	// statusPublished means both the announcement and the summary reply are published.
+	statusPublished actStatus = "published"
+	// statusFailed means publishing was given up after too many attempts.
+	statusFailed actStatus = "failed"
+	// statusArchived means the act was stored without being published.
+	statusArchived actStatus = "archived"
+	// statusMissing means the position was skipped because it was not published when later ones were.
+	statusMissing actStatus = "missing"
+)
+
+type ledgerEntry struct {
+	Year    int       `json:"year"`
+	Nr      int       `json:"nr,omitempty"`
+	Pos     int       `json:"pos"`
+	Title   string    `json:"title,omitempty"`
+	Act     *Act      `json:"act,omitempty"`
+	Summary string    `json:"summary,omitempty"`
+	Links   []actLink `json:"links,omitempty"`
+	// Pages is the number of pages in the act PDF.
+	Pages   int                   `json:"pages,omitempty"`
+	Posts   map[string]targetPost `json:"posts,omitempty"`
+	Status  actStatus             `json:"status"`
+	Created time.Time             `json:"created"`
+	Updated time.Time             `json:"updated"`
+}
+
+// targetPost tracks publishing of an act to a single publisher.
+type targetPost struct {
+	ID        string    `json:"id,omitempty"`
+	SummaryID string    `json:"summary_id,omitempty"`
+	MediaIDs  []string  `json:"media_ids,omitempty"`
+	Status    actStatus `json:"status"`
+	Attempts  int       `json:"attempts,omitempty"`
+	Error     string    `json:"error,omitempty"`
+}
+
+// legacyLedgerEntry holds fields written before publishers were tracked separately.
+type legacyLedgerEntry struct {
+	TweetID        string   `json:"tweet_id,omitempty"`
+	SummaryTweetID string   `json:"summary_tweet_id,omitempty"`
+	MediaIDs       []string `json:"media_ids,omitempty"`
+	Error          string   `json:"error,omitempty"`
+}
+
+func (e *ledgerEntry) UnmarshalJSON(data []byte) error {
+	type entry ledgerEntry
+	var v struct {
+		entry
+		legacyLedgerEntry
+	}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	*e = ledgerEntry(v.entry)
+	if v.TweetID != "" && e.Posts == nil {
+		e.Posts = map[string]targetPost{twitterTarget: {
+			ID:        v.TweetID,
+			SummaryID: v.SummaryTweetID,
+			MediaIDs:  v.MediaIDs,
+			Status:    e.Status,
+			Error:     v.Error,
+		}}
+	}
+	return nil
+}
+
+// published reports whether the act was handled by the publishing pipeline and counts for the cursor.
+func (e ledgerEntry) published() bool {
+	return e.Status != statusArchived && e.Status != statusMissing
+}
+
+// updateStatus derives the act status from statuses of all publishers.
+func (e *ledgerEntry) updateStatus() {
+	if len(e.Posts) == 0 {
+		return
+	}
+	e.Status = statusPublished
+	for _, p := range e.Posts {
+		switch p.Status {
+		case statusPending, statusPosted:
+			e.Status = statusPosted
+			return
+		case statusFailed:
+			e.Status = statusFailed
+		}
+	}
+}
+
+// update copies what is known about the act to the entry.
+func (e *ledgerEntry) update(act newAct) {
+	e.Year, e.Nr, e.Pos, e.Title = act.Year, act.Nr, act.Pos, act.Title
+	if act.Meta.Title != "" {
+		e.Act = &act.Meta
+	}
+	if act.Links != nil {
+		e.Links = act.Links
+	}
+	if len(act.Pages) > 0 {
+		e.Pages = len(act.Pages)
+	}
+}
+
+type actKey struct {
+	Year int
+	Pos  int
+}
+
+func (e ledgerEntry) key() actKey {
+	return actKey{Year: e.Year, Pos: e.Pos}
+}
+
+// ledger is an append-only JSONL log of published acts. Every state change is
+// written as a new line, the latest line for a given act wins.
+type ledger struct {
+	path    string
+	journal *journal
+	entries map[actKey]ledgerEntry
+	// dry keeps recorded entries in memory only, nothing is written to the file.
+	dry bool
+}
+
+func openLedger(path string) (*ledger, error) {
+	l := &ledger{path: path, journal: dziennikUstaw, entries: map[actKey]ledgerEntry{}}
+	err := readJSONL(path, func(e ledgerEntry, _ int) error {
+		if e.Year == 0 || e.Pos == 0 {
+			return errors.New("missing year or position")
+		}
+		l.entries[e.key()] = e
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+func (l *ledger) get(year, pos int) (ledgerEntry, bool) {
+	e, ok := l.entries[actKey{Year: year, Pos: pos}]
+	return e, ok
+}
+
+func (l *ledger) record(e ledgerEntry) error {
+	now := time.Now().UTC()
+	if prev, ok := l.entries[e.key()]; ok {
+		e.Created = prev.Created
+	}
+	if e.Created.IsZero() {
+		e.Created = now
+	}
+	e.Updated = now
+	e.updateStatus()
+
+	if !l.dry {
+		if err := appendJSONL(l.path, e); err != nil {
+			return err
+		}
+	}
+	l.entries[e.key()] = e
+	return nil
+}
+
+// last returns the highest position published in a given year or 0 when nothing was published yet.
+func (l *ledger) last(year int) int {
+	last := 0
+	for k, e := range l.entries {
+		if e.published() && k.Year == year && k.Pos > last {
+			last = k.Pos
+		}
+	}
+	return last
+}
+
+// latest returns the most recently published act in the ledger.
+func (l *ledger) latest() (year, pos int) {
+	for k, e := range l.entries {
+		if !e.published() {
+			continue
+		}
+		if k.Year > year || (k.Year == year && k.Pos > pos) {
+			year, pos = k.Year, k.Pos
+		}
+	}
+	return year, pos
+}
+
+// missing returns positions skipped during discovery, oldest first.
+func (l *ledger) missing() []ledgerEntry {
+	var result []ledgerEntry
+	for _, e := range l.entries {
+		if e.Status == statusMissing {
+			result = append(result, e)
+		}
+	}
+	sortEntries(result)
+	return result
+}
+
+// pending returns acts not yet published to all publishers.
+func (l *ledger) pending() []ledgerEntry {
+	var result []ledgerEntry
+	for _, e := range l.entries {
+		if e.Status == statusPosted {
+			result = append(result, e)
+		}
+	}
+	sortEntries(result)
+	return result
+}
+
+func sortEntries(entries []ledgerEntry) {
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Year != entries[j].Year {
+			return entries[i].Year < entries[j].Year
+		}
+		return entries[i].Pos < entries[j].Pos
+	})
+}
+
+// migrateLastTxt seeds a ledger without published acts with the act stored in the legacy last.txt cursor.
+func (l *ledger) migrateLastTxt(path string) error {
+	if year, _ := l.latest(); year != 0 {
+		return nil
+	}
+	file, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	year, pos := getIdFromTweet(string(file))
+	if year*pos == 0 {
+		return fmt.Errorf("could not parse %s", path)
+	}
+	log.WithField("Year", year).WithField("Pos", pos).Infof("Migrating %s to %s", path, l.path)
+	return l.record(ledgerEntry{
+		Year:   year,
+		Pos:    pos,
+		Status: statusPublished,
+	})
+}
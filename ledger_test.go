@@ -0,0 +1,113 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func Test_ledger(t *testing.T) {
+	t.Parallel()
+	path := filepath.Join(t.TempDir(), ledgerFile)
+	l, err := openLedger(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(l.entries) != 0 {
+		t.Errorf("expected empty ledger")
+	}
+	for _, e := range []ledgerEntry{
+		{Year: 2025, Pos: 2000, Status: statusPublished},
+		{Year: 2026, Pos: 1, Posts: map[string]targetPost{twitterTarget: {ID: "1", Status: statusPosted}}},
+		{Year: 2026, Pos: 2, Posts: map[string]targetPost{twitterTarget: {ID: "2", Status: statusPosted}}},
+		{Year: 2026, Pos: 1, Posts: map[string]targetPost{twitterTarget: {ID: "1", SummaryID: "3", Status: statusPublished}}},
+	} {
+		if err := l.record(e); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	l, err = openLedger(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := l.last(2026); got != 2 {
+		t.Errorf("last(2026) = %d, want 2", got)
+	}
+	if got := l.last(2024); got != 0 {
+		t.Errorf("last(2024) = %d, want 0", got)
+	}
+	if y, p := l.latest(); y != 2026 || p != 2 {
+		t.Errorf("latest() = %d %d, want 2026 2", y, p)
+	}
+	pending := l.pending()
+	if len(pending) != 1 || pending[0].Pos != 2 {
+		t.Errorf("pending() = %v, want only pos 2", pending)
+	}
+	e, ok := l.get(2026, 1)
+	if !ok || e.Status != statusPublished || e.Posts[twitterTarget].SummaryID != "3" || e.Created.IsZero() || e.Created.After(e.Updated) {
+		t.Errorf("get(2026, 1) = %v", e)
+	}
+}
+
+func Test_ledgerTornWrite(t *testing.T) {
+	t.Parallel()
+	path := filepath.Join(t.TempDir(), ledgerFile)
+	content := `{"year":2026,"pos":1,"status":"published"}` + "\n" + `{"year":2026,"pos":2,"sta`
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	l, err := openLedger(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := l.last(2026); got != 1 {
+		t.Errorf("last(2026) = %d, want 1", got)
+	}
+	if err := l.record(ledgerEntry{Year: 2026, Pos: 2, Status: statusPosted}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := openLedger(path); err != nil {
+		t.Errorf("openLedger() after torn write error = %v", err)
+	}
+}
+
+func Test_ledgerCorrupted(t *testing.T) {
+	t.Parallel()
+	path := filepath.Join(t.TempDir(), ledgerFile)
+	content := `{"year":2026,"pos":1,"status":"published"}` + "\nnot json\n" + `{"year":2026,"pos":2,"status":"published"}` + "\n"
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := openLedger(path); err == nil {
+		t.Errorf("expected error for corrupted ledger")
+	}
+}
+
+func Test_ledgerMigrateLastTxt(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	lastTxt := filepath.Join(dir, "last.txt")
+	if err := os.WriteFile(lastTxt, []byte("Dz.U. 2026 poz. 563\n📢Obwieszczenie\nhttps://dziennikustaw.gov.pl/D2026000056301.pdf"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	l, err := openLedger(filepath.Join(dir, ledgerFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := l.migrateLastTxt(lastTxt); err != nil {
+		t.Fatal(err)
+	}
+	if got := l.last(2026); got != 563 {
+		t.Errorf("last(2026) = %d, want 563", got)
+	}
+	if err := os.WriteFile(lastTxt, []byte("Dz.U. 2026 poz. 1"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.migrateLastTxt(lastTxt); err != nil {
+		t.Fatal(err)
+	}
+	if got := l.last(2026); got != 563 {
+		t.Errorf("migration should run only once, last(2026) = %d", got)
+	}
+}
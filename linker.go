@@ -0,0 +1,164 @@
+package main
+
+import (
+	"sort"
+	"unicode"
+	"unicode/utf8"
+)
+
+// linker finds names in a text with an Aho-Corasick automaton. Matches are leftmost-longest and do not
+// overlap, so "Ministra Klimatu i Środowiska" is found instead of "Ministra Klimatu" whatever the order of names.
+type linker struct {
+	names []string
+	nodes []linkerNode
+}
+
+type linkerNode struct {
+	next map[byte]int
+	fail int
+	// name is the index of the name ending at the node, -1 when none.
+	name int
+	// output is the nearest node on the fail chain ending a name, -1 when none.
+	output int
+}
+
+// linkMatch is a name found in the text, Start and End are byte offsets.
+type linkMatch struct {
+	Name       int
+	Start, End int
+}
+
+func newLinker(names []string) *linker {
+	l := &linker{names: names, nodes: []linkerNode{{next: map[byte]int{}, name: -1, output: -1}}}
+	for i, name := range names {
+		if name == "" {
+			continue
+		}
+		n := 0
+		for k := 0; k < len(name); k++ {
+			next, ok := l.nodes[n].next[name[k]]
+			if !ok {
+				next = len(l.nodes)
+				l.nodes = append(l.nodes, linkerNode{next: map[byte]int{}, name: -1, output: -1})
+				l.nodes[n].next[name[k]] = next
+			}
+			n = next
+		}
+		// the first of duplicated names wins
+		if l.nodes[n].name < 0 {
+			l.nodes[n].name = i
+		}
+	}
+
+	// fail links in breadth first order, children of the root fail to the root
+	queue := make([]int, 0, len(l.nodes))
+	for _, child := range l.nodes[0].next {
+		queue = append(queue, child)
+	}
+	for len(queue) > 0 {
+		n := queue[0]
+		queue = queue[1:]
+		for c, child := range l.nodes[n].next {
+			f := l.nodes[n].fail
+			for f > 0 && !l.has(f, c) {
+				f = l.nodes[f].fail
+			}
+			if next, ok := l.nodes[f].next[c]; ok && next != child {
+				f = next
+			} else {
+				f = 0
+			}
+			l.nodes[child].fail = f
+			if l.nodes[f].name >= 0 {
+				l.nodes[child].output = f
+			} else {
+				l.nodes[child].output = l.nodes[f].output
+			}
+			queue = append(queue, child)
+		}
+	}
+	return l
+}
+
+func (l *linker) has(n int, c byte) bool {
+	_, ok := l.nodes[n].next[c]
+	return ok
+}
+
+// all returns every occurrence of the names on word boundaries, accepted filters names, e.g. by validity.
+func (l *linker) all(text string, accepted func(name int) bool) []linkMatch {
+	var matches []linkMatch
+	n := 0
+	for i := 0; i < len(text); i++ {
+		for n > 0 && !l.has(n, text[i]) {
+			n = l.nodes[n].fail
+		}
+		n = l.nodes[n].next[text[i]]
+		for m := n; m > 0; m = l.nodes[m].output {
+			name := l.nodes[m].name
+			if name < 0 {
+				continue
+			}
+			start, end := i+1-len(l.names[name]), i+1
+			if wordBoundary(text, start, end) && (accepted == nil || accepted(name)) {
+				matches = append(matches, linkMatch{Name: name, Start: start, End: end})
+			}
+		}
+	}
+	return matches
+}
+
+// match returns the leftmost-longest non-overlapping occurrences of the names ordered by offset.
+func (l *linker) match(text string, accepted func(name int) bool) []linkMatch {
+	matches := l.all(text, accepted)
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].Start != matches[j].Start {
+			return matches[i].Start < matches[j].Start
+		}
+		if matches[i].End != matches[j].End {
+			return matches[i].End > matches[j].End
+		}
+		return matches[i].Name < matches[j].Name
+	})
+	result := matches[:0]
+	end := 0
+	for _, m := range matches {
+		if m.Start >= end {
+			result = append(result, m)
+			end = m.End
+		}
+	}
+	return result
+}
+
+// wordBoundary reports whether text[start:end] is not a part of a longer word, e.g. "Ministra Klimatu" in "Ministra Klimatus".
+func wordBoundary(text string, start, end int) bool {
+	isWord := func(r rune) bool { return unicode.IsLetter(r) || unicode.IsDigit(r) }
+	if r, _ := utf8.DecodeLastRuneInString(text[:start]); start > 0 && isWord(r) {
+		return false
+	}
+	if r, _ := utf8.DecodeRuneInString(text[end:]); end < len(text) && isWord(r) {
+		return false
+	}
+	return true
+}
+
+// replace returns the text with the matches replaced, the matches must be ordered and not overlap.
+// Matches replaced with an empty string are kept.
+func replace(text string, matches []linkMatch, replacement func(linkMatch) string) string {
+	var b []byte
+	last := 0
+	for _, m := range matches {
+		r := replacement(m)
+		if r == "" {
+			continue
+		}
+		b = append(b, text[last:m.Start]...)
+		b = append(b, r...)
+		last = m.End
+	}
+	if last == 0 {
+		return text
+	}
+	return string(append(b, text[last:]...))
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func Test_linkerMatch(t *testing.T) {
+	t.Parallel()
+	names := []string{"Ministra Klimatu", "Ministra Klimatu i Środowiska", "Ministra Finansów", "Ministra Finansów, Funduszy i Polityki Regionalnej", "Środowiska", "Rady"}
+	// offsets are in bytes
+	tests := []struct {
+		text string
+		want []linkMatch
+	}{
+		{"Rozporządzenie Ministra Klimatu i Środowiska", []linkMatch{{Name: 1, Start: 16, End: 46}}},
+		{"Rozporządzenie Ministra Klimatu", []linkMatch{{Name: 0, Start: 16, End: 32}}},
+		{"Ministra Finansów, Funduszy i Polityki Regionalnej oraz Ministra Finansów", []linkMatch{{Name: 3, Start: 0, End: 51}, {Name: 2, Start: 57, End: 75}}},
+		{"ochrony Środowiska i Rady", []linkMatch{{Name: 4, Start: 8, End: 19}, {Name: 5, Start: 22, End: 26}}},
+		// names inside longer words are not matched
+		{"Radykalna zmiana Ministra Klimatus", nil},
+		{"", nil},
+	}
+	l := newLinker(names)
+	for _, tt := range tests {
+		if got := l.match(tt.text, nil); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("match(%q) = %v, want %v", tt.text, got, tt.want)
+		}
+	}
+
+	// a rejected longer name leaves the shorter one
+	got := l.match("Ministra Klimatu i Środowiska", func(name int) bool { return name != 1 })
+	want := []linkMatch{{Name: 0, Start: 0, End: 16}, {Name: 4, Start: 19, End: 30}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("match() without the longest name = %v, want %v", got, want)
+	}
+}
+
+func Test_replace(t *testing.T) {
+	t.Parallel()
+	l := newLinker([]string{"Ministra Zdrowia", "Ministra Sportu"})
+	text := "Rozporządzenie Ministra Zdrowia i Ministra Sportu"
+	handles := []string{"@MZ_GOV_PL", ""}
+	if got := replace(text, l.match(text, nil), func(m linkMatch) string { return handles[m.Name] }); got != "Rozporządzenie @MZ_GOV_PL i Ministra Sportu" {
+		t.Errorf("replace() = %q", got)
+	}
+}
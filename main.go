@@ -6,15 +6,17 @@ import (
 	"crypto/tls"
 	_ "embed"
 	"errors"
+	"flag"
 	"fmt"
 	"image/jpeg"
 	"io"
-	"io/ioutil"
+	"io/fs"
 	"net/http"
 	"os"
 	"regexp"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	oldApi "github.com/dghubble/go-twitter/twitter"
@@ -28,14 +30,17 @@ import (
 	"github.com/avast/retry-go"
 	"github.com/gen2brain/go-fitz"
 
-	"github.com/openai/openai-go/v2"
 	"github.com/pkoukk/tiktoken-go"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 const url = "https://dziennikustaw.gov.pl"
 
 var (
 	userID = "1334198651141361666"
+	warsaw = "535f0c2de0121451"
 )
 
 type authorizer struct{}
@@ -43,77 +48,148 @@ type authorizer struct{}
 func (a *authorizer) Add(_ *http.Request) {}
 
 func main() {
+	flag.Usage = usage
+	configFile := flag.String("config", os.Getenv("DU_CONFIG"), "YAML or TOML config file, environment variables override it, du.yaml or du.toml by default")
+	flag.Parse()
 
 	log.SetLevel(log.DebugLevel)
 
 	log.Info("Dziennik Ustaw")
 
-	ctx := context.Background()
-
-	config := oauth1.NewConfig(os.Getenv("consumerKey"), os.Getenv("consumerSecret"))
-	token := oauth1.NewToken(os.Getenv("accessToken"), os.Getenv("accessSecret"))
-	// http.Client will automatically authorize Requests
-	httpClient := config.Client(oauth1.NoContext, token)
+	if path, err := loadConfig(*configFile); err != nil {
+		log.WithError(err).Fatal("Could not load config")
+	} else if path != "" {
+		log.WithField("Path", path).Info("Loaded config")
+	}
 
-	// Twitter client
-	client := &twitter.Client{
-		Authorizer: &authorizer{},
-		Client:     httpClient,
-		Host:       "https://api.twitter.com",
+	shutdownTracing, err := setupTracing(context.Background())
+	if err != nil {
+		log.WithError(err).Fatal("Could not set up tracing")
 	}
-	oldClient := oldApi.NewClient(httpClient)
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := shutdownTracing(ctx); err != nil {
+			log.WithError(err).Warn("Could not export traces")
+		}
+	}()
+
+	runCommand(flag.Args())
+}
+
+// run checks for new acts once and publishes them, it is the whole bot pipeline. A failed journal
+// does not stop publishing of the others.
+func run(ctx context.Context) error {
+	client, oldClient := newTwitterClients()
 
 	if err := retweets(client, ctx); err != nil {
 		log.WithError(err).Warn("Failed handle retweets")
 	}
 
-	newActs, summaries, err := prepareNewActs(oldClient)
-	if err != nil {
-		log.WithError(err).Fatal("Could not prepare new acts")
+	if _, ok := os.LookupEnv("DRY"); !ok {
+		if err := answerMentions(ctx, &twitterPublisher{client: client, old: oldClient}); err != nil {
+			log.WithError(err).Warn("Failed to answer mentions")
+		}
 	}
 
-	log.WithField("NewActs", len(newActs)).Info("Publishing tweets")
-	if _, ok := os.LookupEnv("DRY"); ok {
-		log.Warn("DRY RUN")
-		return
+	enabled, err := enabledJournals()
+	if err != nil {
+		return fmt.Errorf("could not configure journals: %w", err)
 	}
-	for i, tw := range append(newActs) {
-		t, err := client.CreateTweet(ctx, tw)
-		if err != nil {
-			log.WithError(err).Fatal("Could not publish tweet")
-		}
-		log.WithFields(logLimit(t.RateLimit)).WithField("Text", t.Tweet.Text).Info("Published")
-		err = os.WriteFile("last.txt", []byte(tw.Text), 0x777)
-		if err != nil {
-			log.WithError(err).Fatal("Could save published tweet")
+	publishers, err := newPublishers(client, oldClient)
+	if err != nil {
+		return err
+	}
+	var errs []error
+	for _, j := range enabled {
+		if err := publishJournal(ctx, j, publishers); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", j.Code, err))
 		}
+	}
+	return errors.Join(errs...)
+}
 
-		summary, err := summaries[i]()
-		if err != nil {
-			log.WithField("summary", summary).WithError(err).Error("Could not get tweet summary")
-			continue
+// publishJournal publishes new acts of a single journal, every journal has its own ledger and cursor.
+func publishJournal(ctx context.Context, j *journal, publishers []publisher) error {
+	logger := log.WithField("Journal", j.Code)
+	l, err := j.openLedger()
+	if err != nil {
+		return fmt.Errorf("could not open ledger: %w", err)
+	}
+	_, dry := os.LookupEnv("DRY")
+	// a dry run seeds an empty ledger in memory only
+	l.dry = dry
+	d := newDiscovery(j)
+	if err := l.migrateLastTxt(j.Cursor); errors.Is(err, fs.ErrNotExist) {
+		if err := d.seed(ctx, l); err != nil {
+			return fmt.Errorf("could not find the newest published act, put the last published one in %s, e.g. %q: %w", j.Cursor, j.header(time.Now().Year(), 123), err)
 		}
+	} else if err != nil {
+		return fmt.Errorf("could not migrate %s: %w", j.Cursor, err)
+	}
 
-		warsaw := "535f0c2de0121451"
-		summaryTweet := twitter.CreateTweetRequest{
-			ForSuperFollowersOnly: false,
-			Reply: &twitter.CreateTweetReply{
-				InReplyToTweetID: t.Tweet.ID,
-			},
-			Text: summary,
-			Geo: &twitter.CreateTweetGeo{
-				PlaceID: warsaw,
-			},
-		}
-		s, err := client.CreateTweet(ctx, summaryTweet)
-		if err != nil {
-			log.WithField("summary", summary).WithError(err).Error("Could not publish tweet summary")
-			continue
+	newActs, gaps, err := prepareNewActs(ctx, l, d)
+	if err != nil {
+		return fmt.Errorf("could not prepare new acts: %w", err)
+	}
+
+	actsDiscovered.WithLabelValues(j.Code).Add(float64(len(newActs)))
+	logger.WithField("NewActs", len(newActs)).Info("Publishing tweets")
+	if dry {
+		logger.Warn("DRY RUN")
+		return nil
+	}
+	if err := recordGaps(l, gaps, newActs); err != nil {
+		return fmt.Errorf("could not save skipped positions: %w", err)
+	}
+
+	if err := resumePending(ctx, l, publishers); err != nil {
+		return err
+	}
+	for _, act := range newActs {
+		if err := publishAct(ctx, l, act, publishers); err != nil {
+			return err
 		}
-		log.WithFields(logLimit(t.RateLimit)).WithField("Text", s.Tweet.Text).Info("Published")
+	}
+	if err := notifySubscribers(ctx, l); err != nil {
+		logger.WithError(err).Error("Could not notify subscribers")
+	}
 
+	if err := writeFeeds(l, j.Feed); err != nil {
+		logger.WithError(err).Error("Could not write feeds")
+	}
+	return nil
+}
+
+func newTwitterClients() (*twitter.Client, *oldApi.Client) {
+	config := oauth1.NewConfig(os.Getenv("consumerKey"), os.Getenv("consumerSecret"))
+	token := oauth1.NewToken(os.Getenv("accessToken"), os.Getenv("accessSecret"))
+	// http.Client will automatically authorize Requests
+	traced := context.WithValue(oauth1.NoContext, oauth1.HTTPClient, &http.Client{Transport: tracedTransport(nil)})
+	httpClient := config.Client(traced, token)
+
+	// Twitter client
+	client := &twitter.Client{
+		Authorizer: &authorizer{},
+		Client:     httpClient,
+		Host:       "https://api.twitter.com",
 	}
+	return client, oldApi.NewClient(httpClient)
+}
 
+func newPublishers(client *twitter.Client, oldClient *oldApi.Client) ([]publisher, error) {
+	publishers := []publisher{&twitterPublisher{client: client, old: oldClient}}
+	if masto := newMastodonFromEnv(); masto != nil {
+		publishers = append(publishers, masto)
+	}
+	bsky, err := newBlueskyFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	if bsky != nil {
+		publishers = append(publishers, bsky)
+	}
+	return publishers, nil
 }
 
 func retweets(client *twitter.Client, ctx context.Context) error {
@@ -130,231 +206,177 @@ func retweets(client *twitter.Client, ctx context.Context) error {
 	return nil
 }
 
-func prepareNewActs(old *oldApi.Client) ([]twitter.CreateTweetRequest, []func() (string, error), error) {
-	lastTweetedYear, lastTweetedId := getLastId()
-	if lastTweetedYear*lastTweetedId == 0 {
-		log.WithField("Year", lastTweetedYear).WithField("Pos", lastTweetedId).Fatal("There is a problem with obtaining last tweeted act")
+type newAct struct {
+	Journal *journal
+	Year    int
+	Nr      int
+	Pos     int
+	Title   string
+	Meta    Act
+	Pages   [][]byte
+	Summary func() (string, error)
+	// Links are acts amended, repealed or implemented by the act.
+	Links []actLink
+	// Text is extracted from the PDF.
+	Text string
+	// Trace is the span of the act, publishing is traced in the same trace as fetching.
+	Trace trace.SpanContext
+}
+
+// journal returns the journal that published the act, Dziennik Ustaw if unset.
+func (a newAct) journal() *journal {
+	if a.Journal == nil {
+		return dziennikUstaw
 	}
-	year := time.Now().Year()
-	if year != lastTweetedYear {
-		lastTweetedId = 0
+	return a.Journal
+}
+
+func prepareNewActs(ctx context.Context, l *ledger, d *discovery) ([]newAct, []actKey, error) {
+	lastTweetedYear, _ := l.latest()
+	if lastTweetedYear == 0 {
+		return nil, nil, fmt.Errorf("no published acts in %s, put the last published act in %s, e.g. %q", l.path, d.journal.Cursor, d.journal.header(time.Now().Year(), 123))
 	}
+	year := time.Now().Year()
 
-	log.WithField("Current Year", year).Infof("Last tweeted act Dz.U %d pos %d", lastTweetedYear, lastTweetedId)
+	log.WithField("Current Year", year).Infof("Last tweeted act %s %d pos %d", d.journal.Prefix, lastTweetedYear, l.last(year))
 
-	var newActs []twitter.CreateTweetRequest
-	var summaries []func() (string, error)
-	for i := 0; i < 3; i++ {
-		lastTweetedId++
+	discoverCtx, span := startSpan(ctx, "discover", trace.WithAttributes(attribute.String("act.journal", d.journal.Code)))
+	result, err := d.discover(discoverCtx, l, year)
+	endSpan(span, err)
+	if err != nil {
+		return nil, nil, err
+	}
 
-		tweetText := getTweetText(year, 0, lastTweetedId)
-		if tweetText == "" {
-			log.WithField("Year", year).WithField("Pos", lastTweetedId).Info("No data")
-			break
-		}
-		r, err := getPDF(year, 0, lastTweetedId)
+	var newActs []newAct
+	for _, k := range result.Found {
+		// every act has its own trace
+		actCtx, span := startSpan(ctx, "act", trace.WithNewRoot(), actAttributes(d.journal.Code, k.Year, k.Pos))
+		meta := result.Meta[k]
+		act, ok, err := d.journal.fetchParsedAct(actCtx, k.Year, k.Pos, &meta)
+		act.Trace = span.SpanContext()
+		endSpan(span, err)
 		if err != nil {
 			return nil, nil, err
 		}
-		defer r.Body.Close()
-		doc, err := fitz.NewFromReader(r.Body)
-		if err != nil {
-			return nil, nil, err
-		}
-		defer doc.Close()
-
-		mediaIds, err := uploadImages(doc, old)
-		if err != nil {
-			return nil, nil, fmt.Errorf("could not upload images: %w", err)
-		}
-
-		text, err := getPDFText(doc)
-		if err != nil {
-			return nil, nil, fmt.Errorf("could not get pdf text: %w", err)
-		}
-		summary := func() (string, error) { return getTweetSummary(context.Background(), text) }
-		summaries = append(summaries, summary)
-
-		log.WithField("Text", tweetText).Info("Prepared")
-		var media *twitter.CreateTweetMedia
-		if len(mediaIds) > 0 {
-			media = &twitter.CreateTweetMedia{
-				IDs: mediaIds,
-			}
+		if !ok {
+			log.WithField("Year", k.Year).WithField("Pos", k.Pos).Info("No data")
+			continue
 		}
-		warsaw := "535f0c2de0121451"
-		newActs = append(newActs, twitter.CreateTweetRequest{
-			ForSuperFollowersOnly: false,
-			Text:                  tweetText,
-			Media:                 media,
-			Geo: &twitter.CreateTweetGeo{
-				PlaceID: warsaw,
-			},
-		})
+		log.WithField("Text", d.journal.prepareTweet(act.Year, act.Nr, act.Pos, act.Title)).WithField("Pages", len(act.Pages)).Info("Prepared")
+		newActs = append(newActs, act)
 	}
 
-	if len(newActs) != len(summaries) {
-		return nil, nil, fmt.Errorf("could not create new acts, length mismatch")
-	}
-
-	return newActs, summaries, nil
+	return newActs, result.Gaps, nil
 }
 
-var client = &http.Client{Transport: &http.Transport{
+var client = &http.Client{Transport: tracedTransport(&http.Transport{
 	TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
-}}
+})}
 
-func getTweetText(year, nr, pos int) string {
-	var r *http.Response
-	err := retry.Do(func() error {
-		var err error
-		req, err := http.NewRequest("GET", fmt.Sprintf("%s/DU/%d/%d", url, year, pos), nil)
-		if err != nil {
-			return err
-		}
-		req.Header.Set("User-Agent", "curl/7.58.0")
-		req.Header.Set("Accept", "*/*")
-		r, err = client.Do(req)
-		if err != nil {
-			return err
-		}
-		if r.StatusCode != http.StatusOK {
-			body, err := io.ReadAll(r.Body)
-			if err != nil {
-				log.WithField("URL", url).WithField("Status", r.StatusCode).WithField("body", string(body)).Debug("Body")
-			}
-			return fmt.Errorf("unexpected status: %s", r.Status)
-		}
-		return err
-	})
+func getTweetText(year, nr, pos int) (string, error) {
+	title, err := getTitle(year, nr, pos)
+	if err != nil || title == "" {
+		return "", err
+	}
+	return prepareTweet(year, nr, pos, title), nil
+}
+
+func getTitle(year, nr, pos int) (string, error) {
+	page, err := getActPage(year, pos)
 	if err != nil {
-		log.WithError(err).Fatal("Could not get data from Dz.U.")
+		return "", err
 	}
-	title := getTitleFromPage(r.Body)
-	if title == "" {
-		return ""
+	return getTitleFromPage(io.NopCloser(bytes.NewReader(page))), nil
+}
+
+func getActPage(year, pos int) ([]byte, error) {
+	page, err := dzu.page(context.Background(), year, pos)
+	if err != nil {
+		return nil, fmt.Errorf("could not get data from Dz.U.: %w", err)
 	}
-	return prepareTweet(year, nr, pos, title)
+	return page, nil
 }
 
+var pdfNameRegexp = regexp.MustCompile(`[DM](\d{4})(\d{3})(\d{4})\d{2}\.pdf`)
+
 //go:embed prompt.txt
 var prompt string
 
-func getTweetSummary(ctx context.Context, text string) (summary string, err error) {
-	if !checkTokenLength(text, 270000) {
-		return "", retry.Unrecoverable(errors.New("text too long"))
+func getTweetSummary(ctx context.Context, text string) (string, error) {
+	s, err := llm()
+	if err != nil {
+		return "", err
 	}
+	return summarize(ctx, s, text)
+}
 
-	var messages []openai.ChatCompletionMessageParamUnion
-	messages = append(messages, openai.SystemMessage(prompt))
-	messages = append(messages, openai.UserMessage(text))
+func summarize(ctx context.Context, s summarizer, text string) (summary string, err error) {
+	ctx, span := startSpan(ctx, "summarize")
+	defer func(started time.Time) {
+		summaryDuration.Observe(time.Since(started).Seconds())
+		endSpan(span, err)
+	}(time.Now())
+	text, err = condense(ctx, s, text)
+	if err != nil {
+		return "", err
+	}
+
+	messages := []chatMessage{
+		{Role: "system", Content: prompt},
+		{Role: "user", Content: text},
+	}
 
 	err = retry.Do(func() error {
-		summary, messages, err = _getTweetSummary(ctx, messages)
+		summary, messages, err = _getTweetSummary(ctx, s, messages)
 		return err
 	}, retry.Context(ctx),
 		retry.Attempts(3),
 		retry.OnRetry(func(n uint, err error) {
-			log.WithField("retry", n).WithField("summary", summary).WithField("len", len(summary)).WithError(err).Warn("retry")
+			summaryRetries.Inc()
+			retryEvent(ctx, n, err)
+			log.WithField("retry", n).WithField("summary", summary).WithField("len", tweetLength(summary)).WithError(err).Warn("retry")
 		}))
 	return summary, err
 }
 
-func _getTweetSummary(ctx context.Context, messages []openai.ChatCompletionMessageParamUnion) (string, []openai.ChatCompletionMessageParamUnion, error) {
-	client := openai.NewClient()
-	chatCompletion, err := client.Chat.Completions.New(ctx, openai.ChatCompletionNewParams{
-		Messages: messages,
-		Model:    openai.ChatModelGPT5Nano,
-	})
+func _getTweetSummary(ctx context.Context, s summarizer, messages []chatMessage) (string, []chatMessage, error) {
+	content, err := s.Complete(ctx, messages)
 	if err != nil {
 		return "", messages, err
 	}
-	content := chatCompletion.Choices[0].Message.Content
 
-	if len(content) >= 280 {
+	if length := tweetLength(content); length > twitterMaxLength {
 		// Add the assistant's response and feedback to maintain conversation history
-		messages = append(messages, openai.AssistantMessage(content))
-		messages = append(messages, openai.UserMessage(fmt.Sprintf("To jest %d znaków - za dużo! Skróć do maksymalnie 279 znaków. Usuń niepotrzebne słowa, skróć zdania, ale zachowaj najważniejszą informację.", len(content))))
+		messages = append(messages, chatMessage{Role: "assistant", Content: content})
+		messages = append(messages, chatMessage{Role: "user", Content: fmt.Sprintf("To jest %d znaków - za dużo! Skróć do maksymalnie %d znaków. Usuń niepotrzebne słowa, skróć zdania, ale zachowaj najważniejszą informację.", length, twitterMaxLength)})
 		return content, messages, errors.New("too many characters")
 	}
 	return content, messages, nil
 }
 
-func checkTokenLength(text string, maxTokens int) bool {
-	// Load encoding (use cl100k_base, same as GPT-4/5 models)
-	enc, err := tiktoken.GetEncoding("cl100k_base")
-	if err != nil {
-		log.Fatalf("failed to load encoding: %v", err)
-	}
-
-	// Encode text into tokens
-	tokens := enc.Encode(text, nil, nil)
-
-	// Compare length
-	return len(tokens) <= maxTokens
+func checkTokenLength(text string, maxTokens int) (bool, error) {
+	n, err := countTokens(text)
+	return n <= maxTokens, err
 }
 
-func uploadImages(doc *fitz.Document, client *oldApi.Client) ([]string, error) {
+// tiktokenEncoding is loaded once, cl100k_base is the same as in GPT-4/5 models.
+var tiktokenEncoding = sync.OnceValues(func() (*tiktoken.Tiktoken, error) {
+	return tiktoken.GetEncoding("cl100k_base")
+})
 
-	pages, err := convertPDFToJpgs(doc)
+// countTokens counts OpenAI tokens of the text.
+func countTokens(text string) (int, error) {
+	enc, err := tiktokenEncoding()
 	if err != nil {
-		return nil, err
-	}
-	log.Info("Pages to upload: ", len(pages))
-	mediaIds := make([]string, 0, len(pages))
-	if _, ok := os.LookupEnv("DRY"); ok {
-		return nil, nil
-	}
-	for _, p := range pages {
-		resp, _, err := client.Media.Upload(p, "image/jpeg")
-		if err != nil {
-			return nil, err
-		}
-		mID := resp.MediaIDString
-
-		if resp.ProcessingInfo != nil {
-			log.WithField("MediaID", mID).Debugf("Still processing: %#v", resp.ProcessingInfo)
-			for {
-				time.Sleep(100 * time.Millisecond)
-				log.WithField("MediaID", mID).Debugf("Checking upload status %s", mID)
-				r, _, err := client.Media.Status(resp.MediaID)
-				if err != nil {
-					return nil, err
-				}
-				if r.ProcessingInfo == nil {
-					break
-				}
-				log.WithField("MediaID", mID).Debugf("Still processing: %#v", r.ProcessingInfo)
-			}
-		}
-		log.WithField("MediaID", mID).Debug("Upload Succesful")
-		mediaIds = append(mediaIds, mID)
+		return 0, fmt.Errorf("could not load encoding: %w", err)
 	}
-	return mediaIds, nil
+
+	// Encode text into tokens
+	return len(enc.Encode(text, nil, nil)), nil
 }
 
 func getPDF(year int, nr int, pos int) (r *http.Response, err error) {
-	url := pdfUrl(year, nr, pos)
-	return r, retry.Do(func() error {
-		req, err := http.NewRequest("GET", url, nil)
-		if err != nil {
-			return err
-		}
-		req.Header.Set("User-Agent", "Mozilla/5.0 (Android 4.4; Tablet; rv:41.0) Gecko/41.0 Firefox/41.0")
-		r, err = client.Do(req)
-		log.WithField("URL", url).Infof("GET images")
-		if err != nil {
-			return fmt.Errorf("could not fetch images %w", err)
-		}
-		if r.StatusCode != http.StatusOK {
-			body, err := ioutil.ReadAll(r.Body)
-			if err != nil {
-				log.WithField("URL", url).WithField("Status", r.StatusCode).WithField("body", string(body)).Debug("Body")
-			}
-			return fmt.Errorf("invalid status %s", r.Status)
-		}
-		return nil
-	})
+	return dzu.pdf(context.Background(), year, nr, pos)
 }
 
 const MaxTitleLength = 230
@@ -384,71 +406,20 @@ func getTitleFromPage(body io.ReadCloser) string {
 }
 
 func prepareTweet(year, nr, id int, title string) string {
-	poz := fmt.Sprintf("%d", id)
-	return strings.Join([]string{
-		fmt.Sprintf("Dz.U. %d poz. %s", year, poz), // 22 chars (Dz.U. YYYY poz. XXXX\n)
-		trimTitle(title),     // < 280-22-23 ~ 230 (1 for new line)
-		pdfUrl(year, nr, id), // 23 chars (The current length of a URL in a Tweet is 23 characters, even if the length of the URL would normally be shorter.)
-	}, "\n")
+	return dziennikUstaw.prepareTweet(year, nr, id, title)
 }
 
 func pdfUrl(year, nr, pos int) string {
-	return fmt.Sprintf("%s/D%d%03d%04d01.pdf", url, year, nr, pos)
-}
-
-var handles = map[string]string{
-	"Agencji Restrukturyzacji i Modernizacji Rolnictwa":  "@ARiMR_GOV_PL",
-	"Centralnego Biura Antykorupcyjnego":                 "@CBAgovPL",
-	"Centralnym Biurze Antykorupcyjnym":                  "@CBAgovPL",
-	"Głównego Inspektora Transportu Drogowego":           "@ITD_gov",
-	"Marszałka Sejmu Rzeczypospolitej Polskiej":          "@wlodekczarzasty",
-	"Ministra Aktywów Państwowych":                       "@MAPgovPL",
-	"Ministra Edukacji":                                  "@MEN_GOVPL",
-	"Ministra Finansów ":                                  "@MF_gov_PL ",
-	"Ministra Finansów, Funduszy i Polityki Regionalnej": "@MF_gov_PL",
-	"Ministra Funduszy i Polityki Regionalnej":           "@MFiPR_gov_PL",
-	"Ministra Infrastruktury":                            "@MI_GOV_PL",
-	"Ministra Klimatu i Środowiska":                      "@MKiS_gov_PL",
-	"Ministra Klimatu":                                   "@MKiS_gov_PL",
-	"Ministra Kultury i Dziedzictwa Narodowego":          "@kultura_gov_pl",
-	"Ministra Kultury, Dziedzictwa Narodowego i Sportu":  "@kultura_gov_pl",
-	"Ministra Nauki i Szkolnictwa Wyższego":              "@MEiN_gov_PL", // historycznie MEiN; obecnie rozdział na MEN i MNiSW [web:3][web:5]
-	"Ministra Obrony Narodowej":                          "@MON_GOV_PL",
-	"Ministra Rodziny i Polityki Społecznej":             "@MRiPS_gov_PL", // nazwa historyczna; obecnie „Ministra Rodziny, Pracy i Polityki Społecznej” [web:3][web:5][web:8]
-	"Ministra Rodziny, Pracy i Polityki Społecznej":      "@MRiPS_gov_PL",
-	"Ministra Rolnictwa i Rozwoju Wsi":                   "@MRiRW_gov_PL",
-	"Ministra Rozwoju i Technologii":                     "@MRiTGOVPL",
-	"Ministra Rozwoju, Pracy i Technologii":              "@MRiTGOVPL",
-	"Ministra Sportu":                                    "@sport_gov_pl",
-	"Ministra Spraw Wewnętrznych i Administracji":        "@MSWiA_GOV_PL",
-	"Ministra Spraw Zagranicznych":                       "@MSZ_RP",
-	"Ministra Sprawiedliwości":                           "@MS_GOV_PL",
-	"Ministra Zdrowia":                                   "@MZ_GOV_PL",
-	"Państwowej Komisji Wyborczej":                       "@PanstwKomWyb",
-	"Państwowej Straży Pożarnej":                         "@KGPSP",
-	"Prezesa Rady Ministrów":                             "@PremierRP",
-	"Prezydenta Rzeczypospolitej Polskiej":               "@prezydentpl", // oficjalne konto Prezydenta RP
-	"Straży Granicznej":                                  "@Straz_Graniczna",
-	"Trybunału Konstytucyjnego":                          "@TK_GOV_PL",
+	return dziennikUstaw.pdfUrl(year, nr, pos)
 }
 
-var emojis = map[string]string{
-	"Obwieszczenie": "📢",
-	"Umowa":         "🤝",
-	"Porozumienie":  "🤝",
+func trimTitle(title string) string {
+	return dziennikUstaw.trimTitle(title)
 }
 
-func trimTitle(title string) string {
-	for name, handle := range handles {
-		title = strings.ReplaceAll(title, name, handle)
-	}
-	for word, emoji := range emojis {
-		if strings.HasPrefix(title, word) {
-			title = emoji + title
-		}
-	}
-	runes := []rune(title)
-	if len(runes) < MaxTitleLength {
+// shortenTitle cuts the title on a word boundary so it fits in max characters counted with length including the ellipsis.
+func shortenTitle(title string, max int, length func(string) int) string {
+	if length(title) <= max {
 		return title
 	}
 
@@ -456,7 +427,7 @@ func trimTitle(title string) string {
 	title = ""
 	for _, part := range split {
 		t := title + part + " "
-		if len(t) > MaxTitleLength {
+		if length(t+"…") > max {
 			break
 		}
 		title = t
@@ -465,15 +436,6 @@ func trimTitle(title string) string {
 	return title + "…"
 }
 
-func getLastId() (year, id int) {
-	file, err := os.ReadFile("last.txt")
-	if err != nil {
-		log.WithError(err)
-		return 0, 0
-	}
-	return getIdFromTweet(string(file))
-}
-
 func getIdFromTweet(s string) (year, id int) {
 	a := strings.Split(strings.Split(s, "\n")[0], " ")
 	if len(a) < 4 {
@@ -535,32 +497,12 @@ func convertPDFToJpgs(doc *fitz.Document) ([][]byte, error) {
 	return result, nil
 }
 
+// extractActFromTweet returns the first Dziennik Ustaw act cited in the tweet.
 func extractActFromTweet(tweet string) (year, nr, pos int) {
-	r := regexp.MustCompile(`(?i)Dz\.\s*U\.\s*z?\s*(?P<year>\d{4})?\s*(r\.?)?\s*(Nr\s*(?P<nr>\d{1,3}),?\s*)?(\s*[Pp]oz)?\.((?P<nr>\d{1,3})\.)?\s*(?P<pos>\d{1,4})`)
-	match := r.FindStringSubmatch(tweet) // TODO: Find all matches not just first one
-	for i, name := range r.SubexpNames() {
-		if i > len(match) {
-			return year, nr, pos
+	for _, c := range parseCitations(tweet) {
+		if c.Journal == dziennikUstaw {
+			return c.Year, c.Nr, c.Pos
 		}
-		switch name {
-		case "year":
-			year, _ = strconv.Atoi(match[i])
-		case "nr":
-			if nr != 0 {
-				break
-			}
-			nr, _ = strconv.Atoi(match[i])
-		case "pos":
-			pos, _ = strconv.Atoi(match[i])
-		}
-	}
-	return year, nr, pos
-}
-
-func logLimit(t *twitter.RateLimit) log.Fields {
-	return log.Fields{
-		"Limit":     t.Limit,
-		"Reset":     t.Reset.Time().Sub(time.Now()).String(),
-		"Remaining": t.Remaining,
 	}
+	return 0, 0, 0
 }
@@ -38,7 +38,7 @@ func Test_prepareTweet(t *testing.T) {
 			Pos:   2,
 			Title: "Oświadczenie Rządowe z dnia 18 grudnia 2019 r. w sprawie mocy obowiązującej w relacjach między Rzecząpospolitą Polską a Republiką Islandii Konwencji wielostronnej implementującej środki traktatowego prawa podatkowego mające na celu zapobieganie erozji podstawy opodatkowania i przenoszeniu zysku, sporządzonej w Paryżu dnia 24 listopada 2016 r., oraz jej zastosowania w realizacji postanowień Umowy między Rządem Rzeczypospolitej Polskiej a Rządem Republiki Islandii w sprawie unikania podwójnego opodatkowania i zapobiegania uchylaniu się od opodatkowania w zakresie podatków od dochodu i majątku, sporządzonej w Reykjaviku dnia 19 czerwca 1998 r., oraz w realizacji postanowień Protokołu między Rządem Rzeczypospolitej Polskiej a Rządem Republiki Islandii o zmianie Umowy między Rządem Rzeczypospolitej Polskiej a Rządem Republiki Islandii w sprawie unikania podwójnego opodatkowania i zapobiegania uchylaniu się od opodatkowania w zakresie podatków od dochodu i majątku, sporządzonej w Reykjaviku dnia 19 czerwca 1998 r., podpisanego w Reykjaviku dnia 16 maja 2012 r.",
 			Year:  2020},
-			want: "Dz.U. 2020 poz. 2\nOświadczenie Rządowe z dnia 18 grudnia 2019 r. w sprawie mocy obowiązującej w relacjach między Rzecząpospolitą Polską a Republiką Islandii Konwencji wielostronnej implementującej środki traktatowego prawa podatkowego …\nhttps://dziennikustaw.gov.pl/D2020000000201.pdf",
+			want: "Dz.U. 2020 poz. 2\nOświadczenie Rządowe z dnia 18 grudnia 2019 r. w sprawie mocy obowiązującej w relacjach między Rzecząpospolitą Polską a Republiką Islandii Konwencji wielostronnej implementującej środki traktatowego prawa podatkowego mające na …\nhttps://dziennikustaw.gov.pl/D2020000000201.pdf",
 		},
 		{act: Item{
 			Pos: 241, Nr: 41,
@@ -69,7 +69,7 @@ func Test_trimTweet(t *testing.T) {
 		},
 		{
 			title: "Oświadczenie Rządowe z dnia 18 grudnia 2019 r. w sprawie mocy obowiązującej w relacjach między Rzecząpospolitą Polską a Republiką Islandii Konwencji wielostronnej implementującej środki traktatowego prawa podatkowego mające na celu zapobieganie erozji podstawy opodatkowania i przenoszeniu zysku, sporządzonej w Paryżu dnia 24 listopada 2016 r., oraz jej zastosowania w realizacji postanowień Umowy między Rządem Rzeczypospolitej Polskiej a Rządem Republiki Islandii w sprawie unikania podwójnego opodatkowania i zapobiegania uchylaniu się od opodatkowania w zakresie podatków od dochodu i majątku, sporządzonej w Reykjaviku dnia 19 czerwca 1998 r., oraz w realizacji postanowień Protokołu między Rządem Rzeczypospolitej Polskiej a Rządem Republiki Islandii o zmianie Umowy między Rządem Rzeczypospolitej Polskiej a Rządem Republiki Islandii w sprawie unikania podwójnego opodatkowania i zapobiegania uchylaniu się od opodatkowania w zakresie podatków od dochodu i majątku, sporządzonej w Reykjaviku dnia 19 czerwca 1998 r., podpisanego w Reykjaviku dnia 16 maja 2012 r.",
-			want:  "Oświadczenie Rządowe z dnia 18 grudnia 2019 r. w sprawie mocy obowiązującej w relacjach między Rzecząpospolitą Polską a Republiką Islandii Konwencji wielostronnej implementującej środki traktatowego prawa podatkowego …",
+			want:  "Oświadczenie Rządowe z dnia 18 grudnia 2019 r. w sprawie mocy obowiązującej w relacjach między Rzecząpospolitą Polską a Republiką Islandii Konwencji wielostronnej implementującej środki traktatowego prawa podatkowego mające na …",
 		},
 		{
 			title: "Obwieszczenie Ministra Zdrowia z dnia 21 maja 2020 r. w sprawie ogłoszenia jednolitego tekstu rozporządzenia Ministra Zdrowia w sprawie grzybów dopuszczonych do obrotu lub produkcji przetworów grzybowych, środków spożywczych zawierających grzyby oraz uprawnień klasyfikatora grzybów i grzyboznawcy",
@@ -280,7 +280,10 @@ func Test_Summary(t *testing.T) {
 
 func TestIntegrationGetTweetText(t *testing.T) {
 	t.Parallel()
-	text := getTweetText(1997, 78, 483)
+	text, err := getTweetText(1997, 78, 483)
+	if err != nil {
+		t.Fatal(err)
+	}
 	e := "Dz.U. 1997 poz. 483\nKonstytucja Rzeczypospolitej Polskiej z dnia 2 kwietnia 1997 r. uchwalona przez Zgromadzenie Narodowe w dniu 2 kwietnia 1997 r., przyjęta przez Naród w referendum konstytucyjnym w dniu 25 maja 1997 r., podpisana przez …\nhttps://dziennikustaw.gov.pl/D1997078048301.pdf"
 	if text != e {
 		t.Errorf("Expected %s got %s", e, text)
@@ -309,11 +312,14 @@ func TestIntegrationgetPDF(t *testing.T) {
 
 func TestCheckTokenLenght(t *testing.T) {
 	t.Parallel()
-	ok := checkTokenLength(prompt, 300)
+	ok, err := checkTokenLength(prompt, 300)
+	if err != nil {
+		t.Fatal(err)
+	}
 	if !ok {
 		t.Errorf("Token length check failed")
 	}
-	ok = checkTokenLength(prompt, 30)
+	ok, _ = checkTokenLength(prompt, 30)
 	if ok {
 		t.Errorf("Token length check failed")
 	}
@@ -0,0 +1,229 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"os"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/avast/retry-go"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	mastodonMaxStatusLength = 500
+	// Mastodon counts every URL as 23 characters regardless of its length.
+	mastodonURLLength    = 23
+	mastodonMaxAltLength = 1500
+)
+
+type mastodon struct {
+	server string
+	token  string
+	client *http.Client
+	// pollInterval is how long to wait between media processing status checks.
+	pollInterval time.Duration
+	rateLimit    rateLimit
+}
+
+// newMastodonFromEnv returns a Mastodon client configured with MASTODON_SERVER
+// and MASTODON_TOKEN or nil when Mastodon is not configured.
+func newMastodonFromEnv() *mastodon {
+	server, token := os.Getenv("MASTODON_SERVER"), os.Getenv("MASTODON_TOKEN")
+	if server == "" || token == "" {
+		return nil
+	}
+	return newMastodon(server, token)
+}
+
+func newMastodon(server, token string) *mastodon {
+	return &mastodon{
+		server:       strings.TrimSuffix(server, "/"),
+		token:        token,
+		client:       &http.Client{Timeout: time.Minute, Transport: tracedTransport(nil)},
+		pollInterval: time.Second,
+	}
+}
+
+type mastodonMedia struct {
+	ID  string  `json:"id"`
+	URL *string `json:"url"`
+}
+
+type mastodonStatus struct {
+	ID  string `json:"id"`
+	URL string `json:"url"`
+}
+
+const mastodonTarget = "mastodon"
+
+func (m *mastodon) Name() string {
+	return mastodonTarget
+}
+
+func (m *mastodon) RateLimit() rateLimit {
+	return m.rateLimit
+}
+
+func (m *mastodon) UploadMedia(ctx context.Context, act newAct) ([]string, error) {
+	header := act.journal().header(act.Year, act.Pos)
+	mediaIDs := make([]string, 0, len(act.Pages))
+	for i, page := range act.Pages {
+		alt := fmt.Sprintf("%s, strona %d z %d: %s", header, i+1, len(act.Pages), act.Title)
+		id, err := m.uploadMedia(ctx, page, truncateRunes(alt, mastodonMaxAltLength))
+		if err != nil {
+			return nil, err
+		}
+		mediaIDs = append(mediaIDs, id)
+	}
+	return mediaIDs, nil
+}
+
+func (m *mastodon) Announce(ctx context.Context, act newAct, mediaIDs []string) (string, error) {
+	status, err := m.postStatus(ctx, prepareMastodonStatus(act.journal(), act.Year, act.Nr, act.Pos, act.Title), mediaIDs, "")
+	if err != nil {
+		return "", err
+	}
+	log.WithField("URL", status.URL).Info("Published on Mastodon")
+	return status.ID, nil
+}
+
+func (m *mastodon) Reply(ctx context.Context, parentID, text string) (string, error) {
+	status, err := m.postStatus(ctx, text, nil, parentID)
+	if err != nil {
+		return "", err
+	}
+	log.WithField("URL", status.URL).Info("Published on Mastodon")
+	return status.ID, nil
+}
+
+func prepareMastodonStatus(j *journal, year, nr, pos int, title string) string {
+	header := j.header(year, pos)
+	// header, title and URL are separated by new lines
+	max := mastodonMaxStatusLength - utf8.RuneCountInString(header) - mastodonURLLength - 2
+	return strings.Join([]string{
+		header,
+		shortenTitle(j.addEmoji(title), max, utf8.RuneCountInString),
+		j.pdfUrl(year, nr, pos),
+	}, "\n")
+}
+
+func (m *mastodon) uploadMedia(ctx context.Context, jpg []byte, description string) (string, error) {
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+	h := make(textproto.MIMEHeader)
+	h.Set("Content-Disposition", `form-data; name="file"; filename="page.jpg"`)
+	h.Set("Content-Type", "image/jpeg")
+	part, err := w.CreatePart(h)
+	if err != nil {
+		return "", err
+	}
+	if _, err := part.Write(jpg); err != nil {
+		return "", err
+	}
+	if err := w.WriteField("description", description); err != nil {
+		return "", err
+	}
+	if err := w.Close(); err != nil {
+		return "", err
+	}
+
+	var media mastodonMedia
+	status, err := m.do(ctx, http.MethodPost, "/api/v2/media", w.FormDataContentType(), body.Bytes(), "", &media)
+	if err != nil {
+		return "", err
+	}
+	for status == http.StatusAccepted || status == http.StatusPartialContent {
+		log.WithField("MediaID", media.ID).Debug("Mastodon media still processing")
+		select {
+		case <-ctx.Done():
+			return "", ctx.Err()
+		case <-time.After(m.pollInterval):
+		}
+		status, err = m.do(ctx, http.MethodGet, "/api/v1/media/"+media.ID, "", nil, "", &media)
+		if err != nil {
+			return "", err
+		}
+	}
+	log.WithField("MediaID", media.ID).Debug("Upload Succesful")
+	return media.ID, nil
+}
+
+func (m *mastodon) postStatus(ctx context.Context, text string, mediaIDs []string, inReplyTo string) (mastodonStatus, error) {
+	payload := map[string]any{
+		"status":     text,
+		"visibility": "public",
+		"language":   "pl",
+	}
+	if len(mediaIDs) > 0 {
+		payload["media_ids"] = mediaIDs
+	}
+	if inReplyTo != "" {
+		payload["in_reply_to_id"] = inReplyTo
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return mastodonStatus{}, err
+	}
+	var status mastodonStatus
+	// Idempotency key makes retries safe, the server returns the already created status.
+	key := fmt.Sprintf("%x", sha256.Sum256(body))
+	_, err = m.do(ctx, http.MethodPost, "/api/v1/statuses", "application/json", body, key, &status)
+	return status, err
+}
+
+func (m *mastodon) do(ctx context.Context, method, path, contentType string, body []byte, idempotencyKey string, out any) (int, error) {
+	var status int
+	err := retry.Do(func() error {
+		req, err := http.NewRequestWithContext(ctx, method, m.server+path, bytes.NewReader(body))
+		if err != nil {
+			return retry.Unrecoverable(err)
+		}
+		req.Header.Set("Authorization", "Bearer "+m.token)
+		if contentType != "" {
+			req.Header.Set("Content-Type", contentType)
+		}
+		if idempotencyKey != "" {
+			req.Header.Set("Idempotency-Key", idempotencyKey)
+		}
+		r, err := m.client.Do(req)
+		if err != nil {
+			return err
+		}
+		defer r.Body.Close()
+		data, err := io.ReadAll(r.Body)
+		if err != nil {
+			return err
+		}
+		status = r.StatusCode
+		if rl := parseRateLimit(r.Header.Get("X-RateLimit-Limit"), r.Header.Get("X-RateLimit-Remaining"), r.Header.Get("X-RateLimit-Reset")); rl.Limit > 0 {
+			m.rateLimit = rl
+		}
+		if status >= 300 {
+			err := fmt.Errorf("unexpected status %s: %s", r.Status, data)
+			if status >= 400 && status < 500 && status != http.StatusTooManyRequests {
+				return retry.Unrecoverable(err)
+			}
+			return err
+		}
+		return json.Unmarshal(data, out)
+	}, retry.Context(ctx), retry.Attempts(3), retry.LastErrorOnly(true), traceRetries(ctx))
+	return status, err
+}
+
+func truncateRunes(s string, max int) string {
+	runes := []rune(s)
+	if len(runes) <= max {
+		return s
+	}
+	return string(runes[:max-1]) + "…"
+}
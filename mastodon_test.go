@@ -0,0 +1,143 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"unicode/utf8"
+)
+
+type fakeMastodon struct {
+	mu       sync.Mutex
+	media    map[string]string
+	polls    map[string]int
+	statuses []map[string]any
+}
+
+func newFakeMastodon(t *testing.T) (*fakeMastodon, *httptest.Server) {
+	f := &fakeMastodon{media: map[string]string{}, polls: map[string]int{}}
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /api/v2/media", func(w http.ResponseWriter, r *http.Request) {
+		file, _, err := r.FormFile("file")
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+			return
+		}
+		file.Close()
+		f.mu.Lock()
+		id := fmt.Sprintf("m%d", len(f.media)+1)
+		f.media[id] = r.FormValue("description")
+		f.mu.Unlock()
+		w.WriteHeader(http.StatusAccepted)
+		fmt.Fprintf(w, `{"id":%q,"url":null}`, id)
+	})
+	mux.HandleFunc("GET /api/v1/media/{id}", func(w http.ResponseWriter, r *http.Request) {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		id := r.PathValue("id")
+		f.polls[id]++
+		if f.polls[id] < 2 {
+			w.WriteHeader(http.StatusPartialContent)
+			fmt.Fprintf(w, `{"id":%q,"url":null}`, id)
+			return
+		}
+		fmt.Fprintf(w, `{"id":%q,"url":"https://example.com/%s.jpg"}`, id, id)
+	})
+	mux.HandleFunc("POST /api/v1/statuses", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Idempotency-Key") == "" {
+			http.Error(w, "missing idempotency key", http.StatusBadRequest)
+			return
+		}
+		var status map[string]any
+		if err := json.NewDecoder(r.Body).Decode(&status); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if n := utf8.RuneCountInString(status["status"].(string)); n > mastodonMaxStatusLength {
+			http.Error(w, "too long", http.StatusUnprocessableEntity)
+			return
+		}
+		f.mu.Lock()
+		f.statuses = append(f.statuses, status)
+		id := fmt.Sprintf("s%d", len(f.statuses))
+		f.mu.Unlock()
+		fmt.Fprintf(w, `{"id":%q,"url":"https://example.com/@du/%s"}`, id, id)
+	})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		mux.ServeHTTP(w, r)
+	}))
+	t.Cleanup(srv.Close)
+	return f, srv
+}
+
+func Test_mastodonPublish(t *testing.T) {
+	t.Parallel()
+	f, srv := newFakeMastodon(t)
+	m := newMastodon(srv.URL, "secret")
+	m.pollInterval = 0
+
+	act := newAct{
+		Year:    2026,
+		Pos:     563,
+		Title:   "Obwieszczenie Marszałka Sejmu Rzeczypospolitej Polskiej z dnia 22 kwietnia 2026 r.",
+		Pages:   [][]byte{{0xff, 0xd8}, {0xff, 0xd8}},
+		Summary: func() (string, error) { return "Podsumowanie", nil },
+	}
+	l := publishForTest(t, m, act)
+	if post := l.entries[actKey{2026, 563}].Posts[mastodonTarget]; post.Status != statusPublished || post.ID != "s1" || post.SummaryID != "s2" {
+		t.Errorf("post = %+v", post)
+	}
+
+	if len(f.statuses) != 2 {
+		t.Fatalf("expected 2 statuses got %v", f.statuses)
+	}
+	want := "Dz.U. 2026 poz. 563\n📢Obwieszczenie Marszałka Sejmu Rzeczypospolitej Polskiej z dnia 22 kwietnia 2026 r.\nhttps://dziennikustaw.gov.pl/D2026000056301.pdf"
+	if got := f.statuses[0]["status"]; got != want {
+		t.Errorf("status = %q, want %q", got, want)
+	}
+	if got := f.statuses[0]["media_ids"]; fmt.Sprint(got) != "[m1 m2]" {
+		t.Errorf("media_ids = %v", got)
+	}
+	if got := f.statuses[1]["in_reply_to_id"]; got != "s1" {
+		t.Errorf("in_reply_to_id = %v, want s1", got)
+	}
+	if got := f.statuses[1]["status"]; got != "Podsumowanie" {
+		t.Errorf("summary = %v", got)
+	}
+	if got := f.media["m2"]; !strings.HasPrefix(got, "Dz.U. 2026 poz. 563, strona 2 z 2: Obwieszczenie") {
+		t.Errorf("alt text = %q", got)
+	}
+}
+
+func Test_mastodonUnauthorized(t *testing.T) {
+	t.Parallel()
+	_, srv := newFakeMastodon(t)
+	m := newMastodon(srv.URL, "wrong")
+	act := newAct{Year: 2026, Pos: 1, Title: "Ustawa", Summary: func() (string, error) { return "", nil }}
+	l := publishForTest(t, m, act)
+	if post := l.entries[actKey{2026, 1}].Posts[mastodonTarget]; post.Status != statusPending || post.Error == "" {
+		t.Errorf("post = %+v", post)
+	}
+}
+
+func Test_prepareMastodonStatus(t *testing.T) {
+	t.Parallel()
+	title := strings.Repeat("Umowa między Rządem Rzeczypospolitej Polskiej a Rządem Republiki Islandii ", 10)
+	got := prepareMastodonStatus(dziennikUstaw, 2020, 0, 2, title)
+	// the URL is counted as 23 characters
+	length := utf8.RuneCountInString(got) - utf8.RuneCountInString(pdfUrl(2020, 0, 2)) + mastodonURLLength
+	if length > mastodonMaxStatusLength {
+		t.Errorf("status length %d exceeds %d: %s", length, mastodonMaxStatusLength, got)
+	}
+	if !strings.HasPrefix(got, "Dz.U. 2020 poz. 2\n🤝Umowa") || !strings.Contains(got, "…\n") {
+		t.Errorf("prepareMastodonStatus() = %s", got)
+	}
+}
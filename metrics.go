@@ -0,0 +1,59 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Metrics are registered in the default Prometheus registry, next to the Go runtime and process metrics.
+var (
+	durationBuckets = []float64{0.1, 0.25, 0.5, 1, 2.5, 5, 10, 20, 40, 80}
+
+	actsDiscovered = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "du_acts_discovered_total", Help: "Acts discovered in the journal.",
+	}, []string{"journal"})
+	postsPublished = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "du_posts_total", Help: "Acts published to a target by result.",
+	}, []string{"target", "result"})
+	rateLimitRemaining = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "du_rate_limit_remaining", Help: "Requests remaining in the current rate limit window of a target.",
+	}, []string{"target"})
+	summaryDuration = promauto.NewHistogram(prometheus.HistogramOpts{
+		Name: "du_summary_duration_seconds", Help: "Time to summarize an act.", Buckets: durationBuckets,
+	})
+	summaryRetries = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "du_summary_retries_total", Help: "Summaries retried because they were too long or failed.",
+	})
+	openaiTokens = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "du_openai_tokens_total", Help: "Tokens consumed by the OpenAI API.",
+	}, []string{"model", "type"})
+	pdfDownloadBytes = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "du_pdf_download_bytes_total", Help: "Bytes of act PDFs downloaded.",
+	}, []string{"journal"})
+	pdfDownloadTime = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name: "du_pdf_download_duration_seconds", Help: "Time to download an act PDF.", Buckets: durationBuckets,
+	}, []string{"journal"})
+	runFailures = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "du_run_failures_total", Help: "Daemon runs that failed.",
+	})
+)
+
+// observability registers /metrics and the health endpoints, /healthz reports the process is alive
+// and /readyz that it can do its work, ready returns why it cannot.
+func observability(mux *http.ServeMux, ready func() error) {
+	mux.Handle("GET /metrics", promhttp.Handler())
+	mux.HandleFunc("GET /healthz", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, "ok")
+	})
+	mux.HandleFunc("GET /readyz", func(w http.ResponseWriter, r *http.Request) {
+		if err := ready(); err != nil {
+			http.Error(w, err.Error(), http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Fprintln(w, "ok")
+	})
+}
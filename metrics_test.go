@@ -0,0 +1,45 @@
+package main
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func Test_observability(t *testing.T) {
+	t.Parallel()
+	var notReady error
+	mux := http.NewServeMux()
+	observability(mux, func() error { return notReady })
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	get := func(path string) (int, string) {
+		r, err := http.Get(server.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer r.Body.Close()
+		body, _ := io.ReadAll(r.Body)
+		return r.StatusCode, string(body)
+	}
+	if status, body := get("/healthz"); status != http.StatusOK || body != "ok\n" {
+		t.Errorf("GET /healthz = %d %q", status, body)
+	}
+	if status, body := get("/readyz"); status != http.StatusOK {
+		t.Errorf("GET /readyz = %d %q", status, body)
+	}
+	postsPublished.WithLabelValues("test", "success").Inc()
+	if status, body := get("/metrics"); status != http.StatusOK || !strings.Contains(body, `du_posts_total{result="success",target="test"} 1`+"\n") ||
+		!strings.Contains(body, "# TYPE du_summary_duration_seconds histogram\n") || !strings.Contains(body, "# TYPE go_goroutines gauge\n") {
+		t.Errorf("GET /metrics = %d %q", status, body)
+	}
+
+	notReady = errors.New("could not read DU ledger")
+	if status, body := get("/readyz"); status != http.StatusServiceUnavailable || !strings.Contains(body, "DU ledger") {
+		t.Errorf("GET /readyz = %d %q", status, body)
+	}
+}
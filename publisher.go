@@ -0,0 +1,198 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// maxPublishAttempts is how many runs will try to publish an act to a publisher before giving up.
+const maxPublishAttempts = 3
+
+// publisher is a social network target acts are published to.
+type publisher interface {
+	// Name identifies the publisher in the ledger.
+	Name() string
+	// UploadMedia uploads act pages and returns media IDs to attach to the announcement.
+	UploadMedia(ctx context.Context, act newAct) ([]string, error)
+	// Announce publishes the act announcement and returns its ID.
+	Announce(ctx context.Context, act newAct, mediaIDs []string) (string, error)
+	// Reply publishes text as a reply to the post with given ID and returns the reply ID.
+	Reply(ctx context.Context, parentID, text string) (string, error)
+	// RateLimit reports the rate limit returned by the last request.
+	RateLimit() rateLimit
+}
+
+type rateLimit struct {
+	Limit     int
+	Remaining int
+	Reset     time.Time
+}
+
+func (r rateLimit) fields() log.Fields {
+	if r.Reset.IsZero() {
+		return log.Fields{}
+	}
+	return log.Fields{
+		"Limit":     r.Limit,
+		"Reset":     time.Until(r.Reset).String(),
+		"Remaining": r.Remaining,
+	}
+}
+
+// parseRateLimit reads rate limit headers, names differ between services.
+func parseRateLimit(limit, remaining, reset string) rateLimit {
+	l, err := strconv.Atoi(limit)
+	if err != nil {
+		return rateLimit{}
+	}
+	r, _ := strconv.Atoi(remaining)
+	result := rateLimit{Limit: l, Remaining: r}
+	if t, err := time.Parse(time.RFC3339, reset); err == nil {
+		result.Reset = t
+	} else if s, err := strconv.ParseInt(reset, 10, 64); err == nil {
+		result.Reset = time.Unix(s, 0)
+	}
+	return result
+}
+
+// publishAct publishes the act to all publishers, every step is recorded in
+// the ledger so a failed publisher can be retried in the next run without
+// duplicating posts on the others. An error is returned only when the ledger could not be saved.
+func publishAct(ctx context.Context, l *ledger, act newAct, publishers []publisher) error {
+	ctx, span := startSpan(trace.ContextWithSpanContext(ctx, act.Trace), "publish", actAttributes(act.journal().Code, act.Year, act.Pos))
+	defer span.End()
+	entry, _ := l.get(act.Year, act.Pos)
+	entry.update(act)
+	if entry.Posts == nil {
+		entry.Posts = map[string]targetPost{}
+	}
+	for _, p := range publishers {
+		if _, ok := entry.Posts[p.Name()]; !ok {
+			entry.Posts[p.Name()] = targetPost{Status: statusPending}
+		}
+	}
+	save := func() error {
+		if err := l.record(entry); err != nil {
+			return fmt.Errorf("could not save publishing state: %w", err)
+		}
+		return nil
+	}
+	if err := save(); err != nil {
+		return err
+	}
+
+	for _, p := range publishers {
+		logger := log.WithField("Target", p.Name()).WithField("Year", act.Year).WithField("Pos", act.Pos)
+		post := entry.Posts[p.Name()]
+		if post.Status == statusPublished || post.Status == statusFailed {
+			continue
+		}
+		targetCtx, targetSpan := startSpan(ctx, "publish to "+p.Name(), trace.WithAttributes(attribute.String("target", p.Name())))
+		err := publishTo(targetCtx, p, act, &entry, &post)
+		endSpan(targetSpan, err)
+		rl := p.RateLimit()
+		logger = logger.WithFields(rl.fields())
+		if rl.Limit > 0 {
+			rateLimitRemaining.WithLabelValues(p.Name()).Set(float64(rl.Remaining))
+		}
+		if err != nil {
+			postsPublished.WithLabelValues(p.Name(), "failure").Inc()
+			post.Attempts++
+			post.Error = err.Error()
+			if post.Attempts >= maxPublishAttempts {
+				post.Status = statusFailed
+			}
+			logger.WithError(err).WithField("Attempts", post.Attempts).Error("Could not publish")
+		} else {
+			postsPublished.WithLabelValues(p.Name(), "success").Inc()
+			post.Error = ""
+			logger.Info("Published")
+		}
+		entry.Posts[p.Name()] = post
+		if err := save(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func publishTo(ctx context.Context, p publisher, act newAct, entry *ledgerEntry, post *targetPost) error {
+	if post.ID == "" {
+		if len(post.MediaIDs) == 0 {
+			mediaIDs, err := inSpan(ctx, "upload media", func(ctx context.Context) ([]string, error) {
+				return p.UploadMedia(ctx, act)
+			})
+			if err != nil {
+				return fmt.Errorf("could not upload media: %w", err)
+			}
+			post.MediaIDs = mediaIDs
+		}
+		id, err := inSpan(ctx, "announce", func(ctx context.Context) (string, error) {
+			return p.Announce(ctx, act, post.MediaIDs)
+		})
+		if err != nil {
+			return fmt.Errorf("could not announce: %w", err)
+		}
+		post.ID = id
+		post.Status = statusPosted
+	}
+
+	// the summary is generated once and reused for the other targets
+	if entry.Summary == "" {
+		summary, err := act.Summary()
+		if err != nil {
+			return fmt.Errorf("could not get summary: %w", err)
+		}
+		entry.Summary = summary
+	}
+	summary := entry.Summary
+	id, err := inSpan(ctx, "reply", func(ctx context.Context) (string, error) {
+		return p.Reply(ctx, post.ID, withLinks(summary, act.Links))
+	})
+	if err != nil {
+		return fmt.Errorf("could not publish summary: %w", err)
+	}
+	post.SummaryID = id
+	post.Status = statusPublished
+	return nil
+}
+
+// resumePending retries publishing of acts that were not published to all publishers in previous runs.
+func resumePending(ctx context.Context, l *ledger, publishers []publisher) error {
+	for _, entry := range l.pending() {
+		log.WithField("Year", entry.Year).WithField("Pos", entry.Pos).Info("Resuming publishing")
+		actCtx, span := startSpan(ctx, "act", trace.WithNewRoot(), actAttributes(l.journal.Code, entry.Year, entry.Pos))
+		act, err := loadAct(actCtx, l.journal, entry)
+		act.Trace = span.SpanContext()
+		endSpan(span, err)
+		if err != nil {
+			log.WithError(err).Error("Could not load act")
+			continue
+		}
+		if err := publishAct(ctx, l, act, publishers); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// loadAct fetches the act again to rebuild an act recorded in the ledger.
+func loadAct(ctx context.Context, j *journal, entry ledgerEntry) (newAct, error) {
+	act, found, err := j.fetchAct(ctx, entry.Year, entry.Pos)
+	if err != nil {
+		return newAct{}, err
+	}
+	if !found {
+		return newAct{}, fmt.Errorf("act %s not found", j.header(entry.Year, entry.Pos))
+	}
+	if entry.Summary != "" {
+		act.Summary = func() (string, error) { return entry.Summary, nil }
+	}
+	return act, nil
+}
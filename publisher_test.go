@@ -0,0 +1,123 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"testing"
+)
+
+type fakePublisher struct {
+	name      string
+	failures  int
+	announced []string
+	replies   []string
+}
+
+func (f *fakePublisher) Name() string         { return f.name }
+func (f *fakePublisher) RateLimit() rateLimit { return rateLimit{} }
+
+func (f *fakePublisher) UploadMedia(_ context.Context, act newAct) ([]string, error) {
+	return []string{fmt.Sprintf("media-%d", len(act.Pages))}, nil
+}
+
+func (f *fakePublisher) Announce(_ context.Context, act newAct, mediaIDs []string) (string, error) {
+	if f.failures > 0 {
+		f.failures--
+		return "", errors.New("service unavailable")
+	}
+	f.announced = append(f.announced, act.Title)
+	return fmt.Sprintf("%s-%d", f.name, len(f.announced)), nil
+}
+
+func (f *fakePublisher) Reply(_ context.Context, parentID, text string) (string, error) {
+	f.replies = append(f.replies, parentID+":"+text)
+	return fmt.Sprintf("%s-reply-%d", f.name, len(f.replies)), nil
+}
+
+func publishForTest(t *testing.T, p publisher, act newAct) *ledger {
+	t.Helper()
+	l, err := openLedger(filepath.Join(t.TempDir(), ledgerFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	publishAct(context.Background(), l, act, []publisher{p})
+	return l
+}
+
+func Test_publishAct(t *testing.T) {
+	t.Parallel()
+	l, err := openLedger(filepath.Join(t.TempDir(), ledgerFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ok := &fakePublisher{name: "ok"}
+	flaky := &fakePublisher{name: "flaky", failures: 1}
+	broken := &fakePublisher{name: "broken", failures: maxPublishAttempts}
+	publishers := []publisher{ok, flaky, broken}
+	summaries := 0
+	act := newAct{Year: 2026, Pos: 7, Title: "Ustawa", Summary: func() (string, error) {
+		summaries++
+		return "Podsumowanie", nil
+	}}
+
+	publishAct(context.Background(), l, act, publishers)
+	if e, _ := l.get(2026, 7); e.Status != statusPosted || e.Posts["ok"].Status != statusPublished || e.Posts["flaky"].Status != statusPending {
+		t.Errorf("after first run entry = %+v", e)
+	}
+	if len(l.pending()) != 1 {
+		t.Errorf("expected act to be pending")
+	}
+
+	for i := 1; i < maxPublishAttempts; i++ {
+		publishAct(context.Background(), l, act, publishers)
+	}
+	e, _ := l.get(2026, 7)
+	if e.Status != statusFailed || e.Posts["flaky"].Status != statusPublished || e.Posts["broken"].Status != statusFailed {
+		t.Errorf("after retries entry = %+v", e)
+	}
+	if e.Posts["broken"].Attempts != maxPublishAttempts || e.Posts["broken"].Error == "" {
+		t.Errorf("broken = %+v", e.Posts["broken"])
+	}
+	if len(ok.announced) != 1 || len(flaky.announced) != 1 || len(broken.announced) != 0 {
+		t.Errorf("announced ok=%v flaky=%v broken=%v", ok.announced, flaky.announced, broken.announced)
+	}
+	if flaky.replies[0] != "flaky-1:Podsumowanie" {
+		t.Errorf("flaky replies = %v", flaky.replies)
+	}
+	if e.Summary != "Podsumowanie" {
+		t.Errorf("summary = %q", e.Summary)
+	}
+	// the summary is generated once for all targets and reused in the next runs
+	if summaries != 1 {
+		t.Errorf("summaries = %d, want 1", summaries)
+	}
+	if len(l.pending()) != 0 {
+		t.Errorf("expected nothing pending")
+	}
+}
+
+func Test_ledgerLegacyEntry(t *testing.T) {
+	t.Parallel()
+	var e ledgerEntry
+	if err := e.UnmarshalJSON([]byte(`{"year":2026,"pos":1,"tweet_id":"1","summary_tweet_id":"2","status":"published"}`)); err != nil {
+		t.Fatal(err)
+	}
+	if p := e.Posts[twitterTarget]; p.ID != "1" || p.SummaryID != "2" || p.Status != statusPublished {
+		t.Errorf("posts = %+v", e.Posts)
+	}
+}
+
+func Test_parseRateLimit(t *testing.T) {
+	t.Parallel()
+	if r := parseRateLimit("300", "299", "2026-10-18T10:00:00.000Z"); r.Limit != 300 || r.Remaining != 299 || r.Reset.Hour() != 10 {
+		t.Errorf("parseRateLimit() = %+v", r)
+	}
+	if r := parseRateLimit("3000", "2999", "1792300000"); r.Reset.Unix() != 1792300000 {
+		t.Errorf("parseRateLimit() = %+v", r)
+	}
+	if r := parseRateLimit("", "", ""); r.Limit != 0 {
+		t.Errorf("parseRateLimit() = %+v", r)
+	}
+}
@@ -0,0 +1,284 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	mentionsFile = "mentions.jsonl"
+	// maxActsPerMention limits replies to a single mention so a list of citations does not flood the thread.
+	maxActsPerMention = 3
+	maxMentionsPerRun = 10
+	// maxMentionAge skips mentions the bot missed for too long, e.g. before the first run.
+	maxMentionAge = 24 * time.Hour
+)
+
+type mention struct {
+	ID       string
+	AuthorID string
+	Text     string
+	Created  time.Time
+}
+
+// mentionSource is a service where people ask the bot about acts.
+type mentionSource interface {
+	// Mentions returns mentions newer than sinceID, oldest first.
+	Mentions(ctx context.Context, sinceID string) ([]mention, error)
+	Reply(ctx context.Context, parentID, text string) (string, error)
+}
+
+// mentionReply tracks the answer about a single act, the act announcement is followed by its summary.
+type mentionReply struct {
+	Act       string `json:"act"`
+	ID        string `json:"id,omitempty"`
+	SummaryID string `json:"summary_id,omitempty"`
+}
+
+type mentionEntry struct {
+	ID       string         `json:"id"`
+	Text     string         `json:"text,omitempty"`
+	Replies  []mentionReply `json:"replies,omitempty"`
+	Done     bool           `json:"done"`
+	Attempts int            `json:"attempts,omitempty"`
+	Error    string         `json:"error,omitempty"`
+	Updated  time.Time      `json:"updated"`
+}
+
+// mentionLog is an append-only JSONL log of answered mentions, the latest line for a given mention wins.
+type mentionLog struct {
+	path    string
+	entries map[string]mentionEntry
+}
+
+func openMentionLog(path string) (*mentionLog, error) {
+	m := &mentionLog{path: path, entries: map[string]mentionEntry{}}
+	err := readJSONL(path, func(e mentionEntry, _ int) error {
+		if e.ID == "" {
+			return errors.New("missing mention id")
+		}
+		m.entries[e.ID] = e
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (m *mentionLog) record(e mentionEntry) error {
+	e.Updated = time.Now().UTC()
+	if err := appendJSONL(m.path, e); err != nil {
+		return err
+	}
+	m.entries[e.ID] = e
+	return nil
+}
+
+// sinceID returns the newest mention seen so far.
+func (m *mentionLog) sinceID() string {
+	since := ""
+	for id := range m.entries {
+		if newerID(id, since) {
+			since = id
+		}
+	}
+	return since
+}
+
+// unfinished returns mentions that were not answered because of an error, oldest first.
+func (m *mentionLog) unfinished() []mentionEntry {
+	var result []mentionEntry
+	for _, e := range m.entries {
+		if !e.Done {
+			result = append(result, e)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return newerID(result[j].ID, result[i].ID) })
+	return result
+}
+
+// newerID compares tweet IDs which are numbers too big for some clients so they are passed as strings.
+func newerID(a, b string) bool {
+	if len(a) != len(b) {
+		return len(a) > len(b)
+	}
+	return a > b
+}
+
+// replyBot answers mentions citing acts with the act announcement and its summary.
+type replyBot struct {
+	source   mentionSource
+	mentions *mentionLog
+	// ledgers cache titles and summaries of acts so they are generated once, acts from other journals are not answered.
+	ledgers map[*journal]*ledger
+	fetch   func(ctx context.Context, j *journal, year, pos int) (newAct, bool, error)
+	// self is the bot user ID, the bot does not answer itself.
+	self string
+}
+
+func answerMentions(ctx context.Context, source mentionSource) error {
+	mentions, err := openMentionLog(mentionsFile)
+	if err != nil {
+		return err
+	}
+	journals, err := enabledJournals()
+	if err != nil {
+		return err
+	}
+	ledgers := map[*journal]*ledger{}
+	for _, j := range journals {
+		if ledgers[j], err = j.openLedger(); err != nil {
+			return err
+		}
+	}
+	fetch := func(ctx context.Context, j *journal, year, pos int) (newAct, bool, error) {
+		return j.fetchAct(ctx, year, pos)
+	}
+	bot := &replyBot{source: source, mentions: mentions, ledgers: ledgers, fetch: fetch, self: userID}
+	return bot.run(ctx)
+}
+
+func (b *replyBot) run(ctx context.Context) error {
+	pending := b.mentions.unfinished()
+	mentions, err := b.source.Mentions(ctx, b.mentions.sinceID())
+	if err != nil {
+		return fmt.Errorf("could not get mentions: %w", err)
+	}
+	for _, m := range mentions {
+		if _, ok := b.mentions.entries[m.ID]; ok {
+			continue
+		}
+		e := mentionEntry{ID: m.ID, Text: m.Text}
+		if m.AuthorID == b.self || time.Since(m.Created) > maxMentionAge {
+			e.Done = true
+			if err := b.mentions.record(e); err != nil {
+				return err
+			}
+			continue
+		}
+		pending = append(pending, e)
+	}
+
+	for i, e := range pending {
+		if i == maxMentionsPerRun {
+			log.WithField("Left", len(pending)-i).Info("Too many mentions, the rest will be answered in the next run")
+			break
+		}
+		logger := log.WithField("Mention", e.ID)
+		err := b.answer(ctx, &e)
+		if err != nil {
+			e.Attempts++
+			e.Error = err.Error()
+			e.Done = e.Attempts >= maxPublishAttempts
+			logger.WithError(err).WithField("Attempts", e.Attempts).Error("Could not answer mention")
+		} else {
+			e.Error = ""
+			e.Done = true
+			logger.WithField("Replies", len(e.Replies)).Info("Answered mention")
+		}
+		if err := b.mentions.record(e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// answer replies about every act cited in the mention, progress is saved after every reply so nothing is posted twice.
+func (b *replyBot) answer(ctx context.Context, e *mentionEntry) error {
+	for _, c := range b.citedActs(e.Text) {
+		j := c.Journal
+		header := j.header(c.Year, c.Pos)
+		i := 0
+		for i < len(e.Replies) && e.Replies[i].Act != header {
+			i++
+		}
+		if i == len(e.Replies) {
+			e.Replies = append(e.Replies, mentionReply{Act: header})
+		}
+		reply := &e.Replies[i]
+		if reply.SummaryID != "" {
+			continue
+		}
+
+		entry, found, err := b.lookup(ctx, c)
+		if err != nil {
+			return fmt.Errorf("could not get %s: %w", header, err)
+		}
+		if !found {
+			log.WithField("Mention", e.ID).WithField("Act", header).Info("Cited act not found")
+			e.Replies = e.Replies[:i]
+			continue
+		}
+		if reply.ID == "" {
+			id, err := b.source.Reply(ctx, e.ID, j.prepareTweet(entry.Year, entry.Nr, entry.Pos, entry.Title))
+			if err != nil {
+				return err
+			}
+			reply.ID = id
+			if err := b.mentions.record(*e); err != nil {
+				return err
+			}
+		}
+		if entry.Summary == "" {
+			continue
+		}
+		id, err := b.source.Reply(ctx, reply.ID, withLinks(entry.Summary, entry.Links))
+		if err != nil {
+			return err
+		}
+		reply.SummaryID = id
+		if err := b.mentions.record(*e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// citedActs returns distinct acts cited in the text with known year and position from journals the bot follows.
+func (b *replyBot) citedActs(text string) []citation {
+	var result []citation
+	seen := map[string]bool{}
+	for _, c := range parseCitations(text) {
+		if _, ok := b.ledgers[c.Journal]; !ok || !c.complete() || seen[c.eli()] {
+			continue
+		}
+		seen[c.eli()] = true
+		result = append(result, c)
+		if len(result) == maxActsPerMention {
+			break
+		}
+	}
+	return result
+}
+
+// lookup returns the act from the ledger, acts not seen before are fetched, summarized and archived.
+func (b *replyBot) lookup(ctx context.Context, c citation) (ledgerEntry, bool, error) {
+	l := b.ledgers[c.Journal]
+	entry, ok := l.get(c.Year, c.Pos)
+	if ok && entry.Title != "" && entry.Summary != "" {
+		return entry, true, nil
+	}
+	act, found, err := b.fetch(ctx, c.Journal, c.Year, c.Pos)
+	if err != nil || !found {
+		return ledgerEntry{}, found, err
+	}
+	if !ok {
+		entry = ledgerEntry{Status: statusArchived}
+	}
+	entry.update(act)
+	if summary, err := act.Summary(); err != nil {
+		log.WithError(err).WithField("Year", act.Year).WithField("Pos", act.Pos).Warn("Could not get summary")
+	} else {
+		entry.Summary = summary
+	}
+	if err := l.record(entry); err != nil {
+		return ledgerEntry{}, true, err
+	}
+	return entry, true, nil
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+type fakeMentionSource struct {
+	mentions []mention
+	replies  []string
+}
+
+func (f *fakeMentionSource) Mentions(_ context.Context, sinceID string) ([]mention, error) {
+	var result []mention
+	for _, m := range f.mentions {
+		if newerID(m.ID, sinceID) {
+			result = append(result, m)
+		}
+	}
+	return result, nil
+}
+
+func (f *fakeMentionSource) Reply(_ context.Context, parentID, text string) (string, error) {
+	f.replies = append(f.replies, parentID+": "+text)
+	return fmt.Sprintf("r%d", len(f.replies)), nil
+}
+
+func newTestReplyBot(t *testing.T, source *fakeMentionSource) (*replyBot, *int) {
+	dir := t.TempDir()
+	mentions, err := openMentionLog(filepath.Join(dir, mentionsFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	l, err := openLedger(filepath.Join(dir, ledgerFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	fetches := 0
+	fetch := func(_ context.Context, _ *journal, year, pos int) (newAct, bool, error) {
+		fetches++
+		if pos == 9999 {
+			return newAct{}, false, nil
+		}
+		title := fmt.Sprintf("Ustawa nr %d", pos)
+		return newAct{Year: year, Pos: pos, Title: title, Summary: func() (string, error) { return "Podsumowanie " + title, nil }}, true, nil
+	}
+	ledgers := map[*journal]*ledger{dziennikUstaw: l}
+	return &replyBot{source: source, mentions: mentions, ledgers: ledgers, fetch: fetch, self: "bot"}, &fetches
+}
+
+func Test_replyBot(t *testing.T) {
+	t.Parallel()
+	now := time.Now()
+	source := &fakeMentionSource{mentions: []mention{
+		{ID: "100", AuthorID: "u1", Created: now, Text: "@Dziennik_Ustaw co to jest Dz.U. 2020 poz. 1 i Dz.U. 2021 poz. 2? A także Dz.U. 2020 poz. 1"},
+		{ID: "101", AuthorID: "bot", Created: now, Text: "Dz.U. 2020 poz. 3"},
+		{ID: "102", AuthorID: "u2", Created: now.Add(-48 * time.Hour), Text: "Dz.U. 2020 poz. 4"},
+		{ID: "99", AuthorID: "u3", Created: now, Text: "@Dziennik_Ustaw Dz.U. 2020 poz. 9999 oraz Dz.U. 2020 poz. 1 i M.P. 2020 poz. 7"},
+	}}
+	bot, fetches := newTestReplyBot(t, source)
+	// the summary of an already published act is reused
+	if err := bot.ledgers[dziennikUstaw].record(ledgerEntry{Year: 2021, Pos: 2, Title: "Ustawa budżetowa", Summary: "Budżet", Status: statusPublished}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := bot.run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"100: Dz.U. 2020 poz. 1\nUstawa nr 1\nhttps://dziennikustaw.gov.pl/D2020000000101.pdf",
+		"r1: Podsumowanie Ustawa nr 1",
+		"100: Dz.U. 2021 poz. 2\nUstawa budżetowa\nhttps://dziennikustaw.gov.pl/D2021000000201.pdf",
+		"r3: Budżet",
+		"99: Dz.U. 2020 poz. 1\nUstawa nr 1\nhttps://dziennikustaw.gov.pl/D2020000000101.pdf",
+		"r5: Podsumowanie Ustawa nr 1",
+	}
+	if fmt.Sprintf("%q", source.replies) != fmt.Sprintf("%q", want) {
+		t.Errorf("replies = %q\nwant %q", source.replies, want)
+	}
+	// 2020/1 is fetched once and cached, 9999 is not found, Monitor Polski is not followed
+	if *fetches != 2 {
+		t.Errorf("fetched %d times, want 2", *fetches)
+	}
+	if e, _ := bot.ledgers[dziennikUstaw].get(2020, 1); e.Status != statusArchived || e.Summary != "Podsumowanie Ustawa nr 1" {
+		t.Errorf("cached act = %+v", e)
+	}
+	for _, id := range []string{"99", "100", "101", "102"} {
+		if !bot.mentions.entries[id].Done {
+			t.Errorf("mention %s is not done", id)
+		}
+	}
+
+	// answered mentions are remembered across runs
+	mentions, err := openMentionLog(bot.mentions.path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bot.mentions = mentions
+	if err := bot.run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if len(source.replies) != len(want) {
+		t.Errorf("mentions answered twice: %q", source.replies[len(want):])
+	}
+}
+
+func Test_replyBotResume(t *testing.T) {
+	t.Parallel()
+	source := &fakeMentionSource{mentions: []mention{
+		{ID: "200", AuthorID: "u1", Created: time.Now(), Text: "Dz.U. 2020 poz. 5"},
+	}}
+	bot, _ := newTestReplyBot(t, source)
+
+	// the announcement is posted but the summary fails
+	bot.source = &failingSummary{source}
+	if err := bot.run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	e := bot.mentions.entries["200"]
+	if e.Done || e.Attempts != 1 || len(e.Replies) != 1 || e.Replies[0].ID == "" {
+		t.Fatalf("entry = %+v", e)
+	}
+
+	bot.source = source
+	if err := bot.run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if len(source.replies) != 2 || source.replies[1] != "r1: Podsumowanie Ustawa nr 5" {
+		t.Errorf("replies = %q", source.replies)
+	}
+	if e := bot.mentions.entries["200"]; !e.Done || e.Error != "" {
+		t.Errorf("entry = %+v", e)
+	}
+}
+
+// failingSummary fails replies to the bot's own replies.
+type failingSummary struct {
+	*fakeMentionSource
+}
+
+func (f *failingSummary) Reply(ctx context.Context, parentID, text string) (string, error) {
+	if parentID[0] == 'r' {
+		return "", errors.New("over capacity")
+	}
+	return f.fakeMentionSource.Reply(ctx, parentID, text)
+}
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/avast/retry-go"
+	"github.com/gen2brain/go-fitz"
+	log "github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// site is the journal website serving act pages and PDFs.
+type site struct {
+	base string
+	// code is the journal symbol, PDF names start with its first letter.
+	code   string
+	client *http.Client
+}
+
+var dzu = &site{base: url, code: "DU", client: client}
+
+func (s *site) actPageUrl(year, pos int) string {
+	return fmt.Sprintf("%s/%s/%d/%d", s.base, s.code, year, pos)
+}
+
+func (s *site) pdfUrl(year, nr, pos int) string {
+	return fmt.Sprintf("%s/%s%d%03d%04d01.pdf", s.base, s.code[:1], year, nr, pos)
+}
+
+// page returns the act page, missing acts are served as an error page with status 200.
+func (s *site) page(ctx context.Context, year, pos int) ([]byte, error) {
+	var body []byte
+	err := retry.Do(func() error {
+		req, err := http.NewRequestWithContext(ctx, "GET", s.actPageUrl(year, pos), nil)
+		if err != nil {
+			return retry.Unrecoverable(err)
+		}
+		req.Header.Set("User-Agent", "curl/7.58.0")
+		req.Header.Set("Accept", "*/*")
+		r, err := s.client.Do(req)
+		if err != nil {
+			return err
+		}
+		defer r.Body.Close()
+		body, err = io.ReadAll(r.Body)
+		if r.StatusCode == http.StatusNotFound {
+			body = nil
+			return nil
+		}
+		if r.StatusCode != http.StatusOK {
+			log.WithField("URL", s.base).WithField("Status", r.StatusCode).WithField("body", string(body)).Debug("Body")
+			return fmt.Errorf("unexpected status: %s", r.Status)
+		}
+		return err
+	}, retry.Context(ctx), traceRetries(ctx))
+	return body, err
+}
+
+func (s *site) pdf(ctx context.Context, year, nr, pos int) (r *http.Response, err error) {
+	url := s.pdfUrl(year, nr, pos)
+	return r, retry.Do(func() error {
+		req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+		if err != nil {
+			return retry.Unrecoverable(err)
+		}
+		req.Header.Set("User-Agent", "Mozilla/5.0 (Android 4.4; Tablet; rv:41.0) Gecko/41.0 Firefox/41.0")
+		r, err = s.client.Do(req)
+		log.WithField("URL", url).Infof("GET images")
+		if err != nil {
+			return fmt.Errorf("could not fetch images %w", err)
+		}
+		if r.StatusCode != http.StatusOK {
+			body, err := io.ReadAll(r.Body)
+			r.Body.Close()
+			if err == nil {
+				log.WithField("URL", url).WithField("Status", r.StatusCode).WithField("body", string(body)).Debug("Body")
+			}
+			return fmt.Errorf("invalid status %s", r.Status)
+		}
+		return nil
+	}, retry.Context(ctx), traceRetries(ctx))
+}
+
+// download returns the act PDF, the download is measured in the metrics.
+func (s *site) download(ctx context.Context, year, nr, pos int) (pdf []byte, err error) {
+	ctx, span := startSpan(ctx, "download pdf")
+	defer func() { endSpan(span, err) }()
+	started := time.Now()
+	r, err := s.pdf(ctx, year, nr, pos)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Body.Close()
+	pdf, err = io.ReadAll(r.Body)
+	if err != nil {
+		return nil, fmt.Errorf("could not read pdf: %w", err)
+	}
+	span.SetAttributes(attribute.Int("pdf.bytes", len(pdf)))
+	pdfDownloadBytes.WithLabelValues(s.code).Add(float64(len(pdf)))
+	pdfDownloadTime.WithLabelValues(s.code).Observe(time.Since(started).Seconds())
+	return pdf, nil
+}
+
+// meta returns metadata of the act, the title is empty when the act is not published yet.
+func (s *site) meta(ctx context.Context, year, pos int) (Act, error) {
+	page, err := s.page(ctx, year, pos)
+	if err != nil {
+		return Act{}, err
+	}
+	return parseActPage(bytes.NewReader(page), s.base), nil
+}
+
+// fetchAct downloads the act page, unless meta is already parsed from it, and the PDF. Found is false
+// when the act is not published yet.
+func (s *site) fetchAct(ctx context.Context, year, pos int, meta *Act) (act newAct, found bool, err error) {
+	// the summary is made later, its span belongs to the act and not to fetching
+	actCtx := ctx
+	ctx, span := startSpan(ctx, "fetch")
+	defer func() { endSpan(span, err) }()
+	if meta == nil {
+		page, err := s.meta(ctx, year, pos)
+		if err != nil {
+			return newAct{}, false, err
+		}
+		meta = &page
+	}
+	if meta.Title == "" {
+		return newAct{}, false, nil
+	}
+	nr := meta.Nr
+
+	pdf, err := s.download(ctx, year, nr, pos)
+	if err != nil {
+		return newAct{}, true, err
+	}
+	doc, err := fitz.NewFromMemory(pdf)
+	if err != nil {
+		return newAct{}, true, err
+	}
+	defer doc.Close()
+
+	_, renderSpan := startSpan(ctx, "render")
+	pages, err := convertPDFToJpgs(doc)
+	renderSpan.SetAttributes(attribute.Int("pdf.pages", len(pages)))
+	endSpan(renderSpan, err)
+	if err != nil {
+		return newAct{}, true, fmt.Errorf("could not render pages: %w", err)
+	}
+	_, textSpan := startSpan(ctx, "extract text")
+	text, err := getPDFText(doc)
+	endSpan(textSpan, err)
+	if err != nil {
+		return newAct{}, true, fmt.Errorf("could not get pdf text: %w", err)
+	}
+	return newAct{
+		Year:    year,
+		Nr:      nr,
+		Pos:     pos,
+		Title:   meta.Title,
+		Meta:    *meta,
+		Pages:   pages,
+		Summary: sync.OnceValues(func() (string, error) { return getTweetSummary(actCtx, text) }),
+		Links:   findLinks(fmt.Sprintf("%s/%d/%d", s.code, year, pos), year, text),
+		Text:    text,
+	}, true, nil
+}
@@ -0,0 +1,660 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/smtp"
+	"os"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/avast/retry-go"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	subscriptionsFile = "subscriptions.json"
+	deliveriesFile    = "deliveries.jsonl"
+	// discordMaxLength is the message limit of Discord webhooks.
+	discordMaxLength = 2000
+	// notifyWindow is how long after publishing an act is notified, older acts are not notified when
+	// a subscription is added or the ledger is seeded.
+	notifyWindow = 7 * 24 * time.Hour
+)
+
+// subscription is a rule selecting acts someone wants to be notified about. All given criteria must match,
+// any value of a list criterion is enough.
+type subscription struct {
+	ID string `json:"id"`
+	// Journals are journal codes, e.g. DU or MP, all journals when empty.
+	Journals []string `json:"journals,omitempty"`
+	// Authorities are issuing authorities from the dictionary, e.g. "Ministra Finansów", or their handles, e.g. "@MF_gov_PL".
+	Authorities []string `json:"authorities,omitempty"`
+	// Types are kinds of acts, e.g. Ustawa or Rozporządzenie.
+	Types []string `json:"types,omitempty"`
+	// Keywords are searched in the title, summary and text regardless of inflection and diacritics.
+	Keywords []string `json:"keywords,omitempty"`
+	// Regex is matched against the title and text.
+	Regex  string               `json:"regex,omitempty"`
+	Notify []notificationTarget `json:"notify"`
+	// Created is when the subscription was added, acts published before are not notified.
+	Created time.Time `json:"created,omitzero"`
+
+	regex *regexp.Regexp
+	// names are authority names as they appear in titles.
+	names []string
+}
+
+// notificationTarget is where notifications are delivered, exactly one field is set.
+type notificationTarget struct {
+	Email   string `json:"email,omitempty"`
+	Webhook string `json:"webhook,omitempty"`
+	Slack   string `json:"slack,omitempty"`
+	Discord string `json:"discord,omitempty"`
+}
+
+func (t notificationTarget) String() string {
+	switch {
+	case t.Email != "":
+		return "email:" + t.Email
+	case t.Webhook != "":
+		return "webhook:" + t.Webhook
+	case t.Slack != "":
+		return "slack:" + t.Slack
+	case t.Discord != "":
+		return "discord:" + t.Discord
+	}
+	return ""
+}
+
+// loadSubscriptions reads subscriptions from a JSON file, there are no subscriptions when the file does not exist.
+func loadSubscriptions(path string) ([]subscription, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var subs []subscription
+	if err := json.Unmarshal(data, &subs); err != nil {
+		return nil, fmt.Errorf("could not parse %s: %w", path, err)
+	}
+	ids := map[string]bool{}
+	for i := range subs {
+		if err := subs[i].validate(); err != nil {
+			return nil, fmt.Errorf("invalid subscription %q: %w", subs[i].ID, err)
+		}
+		if ids[subs[i].ID] {
+			return nil, fmt.Errorf("duplicated subscription %q", subs[i].ID)
+		}
+		ids[subs[i].ID] = true
+	}
+	return subs, nil
+}
+
+// saveSubscriptions writes the subscriptions to a temporary file first so a run never reads a partially written file.
+func saveSubscriptions(path string, subs []subscription) error {
+	data, err := json.MarshalIndent(subs, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, append(data, '\n'), 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// addSubscription validates the subscription and adds it to the file.
+func addSubscription(path string, s subscription) error {
+	subs, err := loadSubscriptions(path)
+	if err != nil {
+		return err
+	}
+	if err := s.validate(); err != nil {
+		return err
+	}
+	if slices.ContainsFunc(subs, func(other subscription) bool { return other.ID == s.ID }) {
+		return fmt.Errorf("subscription %q already exists", s.ID)
+	}
+	if s.Created.IsZero() {
+		s.Created = time.Now().UTC()
+	}
+	return saveSubscriptions(path, append(subs, s))
+}
+
+func removeSubscription(path, id string) error {
+	subs, err := loadSubscriptions(path)
+	if err != nil {
+		return err
+	}
+	i := slices.IndexFunc(subs, func(s subscription) bool { return s.ID == id })
+	if i < 0 {
+		return fmt.Errorf("subscription %q not found", id)
+	}
+	return saveSubscriptions(path, slices.Delete(subs, i, i+1))
+}
+
+// listSubscriptions writes a line per subscription with its criteria and targets.
+func listSubscriptions(w io.Writer, subs []subscription) error {
+	for _, s := range subs {
+		var criteria []string
+		for _, c := range []struct {
+			name   string
+			values []string
+		}{{"journals", s.Journals}, {"authorities", s.Authorities}, {"types", s.Types}, {"keywords", s.Keywords}} {
+			if len(c.values) > 0 {
+				criteria = append(criteria, fmt.Sprintf("%s: %s", c.name, strings.Join(c.values, ", ")))
+			}
+		}
+		if s.Regex != "" {
+			criteria = append(criteria, "regex: "+s.Regex)
+		}
+		if len(criteria) == 0 {
+			criteria = []string{"all acts"}
+		}
+		targets := make([]string, 0, len(s.Notify))
+		for _, t := range s.Notify {
+			targets = append(targets, t.String())
+		}
+		if _, err := fmt.Fprintf(w, "%s\t%s\t-> %s\n", s.ID, strings.Join(criteria, "; "), strings.Join(targets, ", ")); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// listFlag is a flag given many times, e.g. -authority "Ministra Finansów" -authority @MZ_GOV_PL.
+type listFlag []string
+
+func (l *listFlag) String() string {
+	return strings.Join(*l, ", ")
+}
+
+func (l *listFlag) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
+
+func subscriptionsCommand(args []string) {
+	fs := flag.NewFlagSet("subscriptions", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: subscriptions [-file file] list | add -id <id> [criteria] <targets> | remove <id>\n")
+		fs.PrintDefaults()
+	}
+	path := fs.String("file", subscriptionsFile, "subscriptions file")
+	fs.Parse(args)
+	if fs.NArg() == 0 {
+		exitUsage(fs, errors.New("missing list, add or remove"))
+	}
+	switch fs.Arg(0) {
+	case "list":
+		subs, err := loadSubscriptions(*path)
+		if err != nil {
+			log.WithError(err).Fatal("Could not load subscriptions")
+		}
+		if err := listSubscriptions(os.Stdout, subs); err != nil {
+			log.WithError(err).Fatal("Could not list subscriptions")
+		}
+	case "add":
+		s, err := parseSubscription(fs.Args()[1:])
+		if err != nil {
+			exitUsage(fs, err)
+		}
+		if err := addSubscription(*path, s); err != nil {
+			log.WithError(err).Fatal("Could not add subscription")
+		}
+		log.WithField("ID", s.ID).Info("Subscription added")
+	case "remove":
+		if fs.NArg() != 2 {
+			exitUsage(fs, errors.New("expected remove <id>"))
+		}
+		if err := removeSubscription(*path, fs.Arg(1)); err != nil {
+			log.WithError(err).Fatal("Could not remove subscription")
+		}
+		log.WithField("ID", fs.Arg(1)).Info("Subscription removed")
+	default:
+		exitUsage(fs, fmt.Errorf("unknown subscriptions command %q", fs.Arg(0)))
+	}
+}
+
+// parseSubscription parses flags of subscriptions add, criteria and targets may be given many times.
+func parseSubscription(args []string) (subscription, error) {
+	var s subscription
+	var emails, webhooks, slacks, discords listFlag
+	fs := flag.NewFlagSet("subscriptions add", flag.ContinueOnError)
+	fs.StringVar(&s.ID, "id", "", "subscription id")
+	fs.Var((*listFlag)(&s.Journals), "journal", "journal code, e.g. DU or MP")
+	fs.Var((*listFlag)(&s.Authorities), "authority", `issuing authority, e.g. "Minister Finansów" or @MF_gov_PL`)
+	fs.Var((*listFlag)(&s.Types), "type", "act type, e.g. Ustawa")
+	fs.Var((*listFlag)(&s.Keywords), "keyword", "keyword searched in the title, summary and text")
+	fs.StringVar(&s.Regex, "regex", "", "regular expression matched against the title and text")
+	fs.Var(&emails, "email", "email address to notify")
+	fs.Var(&webhooks, "webhook", "URL of a JSON webhook to notify")
+	fs.Var(&slacks, "slack", "Slack webhook URL to notify")
+	fs.Var(&discords, "discord", "Discord webhook URL to notify")
+	if err := fs.Parse(args); err != nil {
+		return s, err
+	}
+	if fs.NArg() > 0 {
+		return s, fmt.Errorf("unexpected arguments %q", fs.Args())
+	}
+	for _, email := range emails {
+		s.Notify = append(s.Notify, notificationTarget{Email: email})
+	}
+	for _, url := range webhooks {
+		s.Notify = append(s.Notify, notificationTarget{Webhook: url})
+	}
+	for _, url := range slacks {
+		s.Notify = append(s.Notify, notificationTarget{Slack: url})
+	}
+	for _, url := range discords {
+		s.Notify = append(s.Notify, notificationTarget{Discord: url})
+	}
+	return s, nil
+}
+
+func (s *subscription) validate() error {
+	if s.ID == "" {
+		return errors.New("missing id")
+	}
+	if len(s.Notify) == 0 {
+		return errors.New("missing notify")
+	}
+	for _, t := range s.Notify {
+		if t.String() == "" {
+			return errors.New("empty notification target")
+		}
+	}
+	for _, code := range s.Journals {
+		if _, err := journalByCode(code); err != nil {
+			return err
+		}
+	}
+	for _, authority := range s.Authorities {
+		names := authorityNames(authority)
+		if len(names) == 0 {
+			return fmt.Errorf("unknown authority %q", authority)
+		}
+		s.names = append(s.names, names...)
+	}
+	if s.Regex != "" {
+		r, err := regexp.Compile(s.Regex)
+		if err != nil {
+			return err
+		}
+		s.regex = r
+	}
+	return nil
+}
+
+// authorityNames returns dictionary names of the authority given in any grammatical case, e.g. "Minister Finansów",
+// or by a handle matching all names with the handle.
+func authorityNames(authority string) []string {
+	authority = strings.TrimSpace(authority)
+	handle := "@" + strings.TrimPrefix(authority, "@")
+	d := dictionaries.get()
+	name, _ := d.canonical(authority)
+	seen := map[string]bool{}
+	var names []string
+	for _, in := range d.Institutions {
+		if seen[in.Name] {
+			continue
+		}
+		if in.Name == name || strings.EqualFold(in.Twitter, handle) || strings.EqualFold("@"+in.Bluesky, handle) {
+			seen[in.Name] = true
+			names = append(names, in.Name)
+		}
+	}
+	return names
+}
+
+// subscribedAct is an act checked against subscriptions.
+type subscribedAct struct {
+	Journal *journal
+	Act     newAct
+	Summary string
+	// Published is when the act reached the published status.
+	Published time.Time
+	terms     map[string]bool
+}
+
+// publishedActs returns acts in the ledger published since the time, they are matched against subscriptions
+// whether they were published in this run, resumed later or backfilled.
+func publishedActs(l *ledger, since time.Time) []*subscribedAct {
+	var entries []ledgerEntry
+	for _, e := range l.entries {
+		// acts seeding the ledger have no title and were published before the bot
+		if e.Status == statusPublished && e.Title != "" && !e.Updated.Before(since) {
+			entries = append(entries, e)
+		}
+	}
+	sortEntries(entries)
+	acts := make([]*subscribedAct, 0, len(entries))
+	for _, e := range entries {
+		act := newAct{Journal: l.journal, Year: e.Year, Nr: e.Nr, Pos: e.Pos, Title: e.Title, Links: e.Links}
+		if e.Act != nil {
+			act.Meta = *e.Act
+		}
+		text, err := l.journal.loadText(e.Year, e.Pos)
+		if err != nil {
+			log.WithError(err).WithField("Year", e.Year).WithField("Pos", e.Pos).Warn("Could not load act text")
+		}
+		act.Text = text
+		acts = append(acts, &subscribedAct{Journal: l.journal, Act: act, Summary: e.Summary, Published: e.Updated})
+	}
+	return acts
+}
+
+func (s subscription) match(a *subscribedAct) bool {
+	if !s.Created.IsZero() && a.Published.Before(s.Created) {
+		return false
+	}
+	if len(s.Journals) > 0 && !containsFold(s.Journals, a.Journal.Code) {
+		return false
+	}
+	if len(s.names) > 0 {
+		d := dictionaries.get()
+		authorities := a.Act.Meta.Institutions
+		if len(authorities) == 0 {
+			authorities = d.authorities(a.Journal, a.Act.Title)
+		}
+		if name, ok := d.canonical(a.Act.Meta.Authority); ok {
+			authorities = append(slices.Clip(authorities), name)
+		}
+		found := false
+		for _, name := range s.names {
+			found = found || slices.Contains(authorities, name)
+		}
+		if !found {
+			return false
+		}
+	}
+	if len(s.Types) > 0 {
+		kind := a.Act.Meta.Type
+		if kind == "" {
+			kind, _, _ = strings.Cut(a.Act.Title, " ")
+		}
+		if !containsFold(s.Types, kind) {
+			return false
+		}
+	}
+	if len(s.Keywords) > 0 {
+		if a.terms == nil {
+			a.terms = map[string]bool{}
+			for _, term := range analyze(a.Act.Title + "\n" + a.Summary + "\n" + a.Act.Text) {
+				a.terms[term] = true
+			}
+		}
+		found := false
+		for _, keyword := range s.Keywords {
+			all := true
+			for _, term := range analyze(keyword) {
+				all = all && a.terms[term]
+			}
+			found = found || all
+		}
+		if !found {
+			return false
+		}
+	}
+	if s.regex != nil && !s.regex.MatchString(a.Act.Title+"\n"+a.Act.Text) {
+		return false
+	}
+	return true
+}
+
+func containsFold(values []string, v string) bool {
+	for _, value := range values {
+		if strings.EqualFold(value, v) {
+			return true
+		}
+	}
+	return false
+}
+
+// notification is the message about an act, it is also the payload of generic webhooks.
+type notification struct {
+	Subscription string `json:"subscription"`
+	ELI          string `json:"eli"`
+	Header       string `json:"header"`
+	Title        string `json:"title"`
+	Summary      string `json:"summary,omitempty"`
+	URL          string `json:"url"`
+	PDF          string `json:"pdf"`
+}
+
+func (n notification) text(bold string) string {
+	lines := []string{bold + n.Header + bold, n.Title}
+	if n.Summary != "" {
+		lines = append(lines, n.Summary)
+	}
+	return strings.Join(append(lines, n.URL), "\n")
+}
+
+// delivery tracks a notification sent to a single target, the latest line for a given ID wins.
+type delivery struct {
+	ID           string             `json:"id"`
+	Notification notification       `json:"notification"`
+	Target       notificationTarget `json:"target"`
+	Done         bool               `json:"done"`
+	Attempts     int                `json:"attempts,omitempty"`
+	Error        string             `json:"error,omitempty"`
+	Updated      time.Time          `json:"updated"`
+}
+
+type deliveryLog struct {
+	path    string
+	entries map[string]delivery
+}
+
+func openDeliveryLog(path string) (*deliveryLog, error) {
+	d := &deliveryLog{path: path, entries: map[string]delivery{}}
+	err := readJSONL(path, func(e delivery, _ int) error {
+		if e.ID == "" {
+			return errors.New("missing delivery id")
+		}
+		d.entries[e.ID] = e
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+func (d *deliveryLog) record(e delivery) error {
+	e.Updated = time.Now().UTC()
+	if err := appendJSONL(d.path, e); err != nil {
+		return err
+	}
+	d.entries[e.ID] = e
+	return nil
+}
+
+// notifier delivers notifications to a single kind of target.
+type notifier interface {
+	Notify(ctx context.Context, target notificationTarget, n notification) error
+}
+
+// subscribers sends notifications about new acts matching subscriptions, every delivery is recorded so nobody
+// is notified twice and failed deliveries are retried in the next run.
+type subscribers struct {
+	subscriptions []subscription
+	deliveries    *deliveryLog
+	email         notifier
+	webhook       notifier
+}
+
+// notifySubscribers notifies about acts published recently in the ledger.
+func notifySubscribers(ctx context.Context, l *ledger) error {
+	subs, err := loadSubscriptions(subscriptionsFile)
+	if err != nil || len(subs) == 0 {
+		return err
+	}
+	deliveries, err := openDeliveryLog(deliveriesFile)
+	if err != nil {
+		return err
+	}
+	n := &subscribers{subscriptions: subs, deliveries: deliveries, webhook: newWebhookNotifier()}
+	if email := newSMTPFromEnv(); email != nil {
+		n.email = email
+	}
+	return n.run(ctx, publishedActs(l, time.Now().Add(-notifyWindow)))
+}
+
+func (n *subscribers) run(ctx context.Context, acts []*subscribedAct) error {
+	var pending []delivery
+	for _, e := range n.deliveries.entries {
+		if !e.Done {
+			pending = append(pending, e)
+		}
+	}
+	sort.Slice(pending, func(i, j int) bool { return pending[i].Updated.Before(pending[j].Updated) })
+	for _, a := range acts {
+		j, act := a.Journal, a.Act
+		eli := citation{Journal: j, Year: act.Year, Pos: act.Pos}.eli()
+		for _, s := range n.subscriptions {
+			if !s.match(a) {
+				continue
+			}
+			msg := notification{
+				Subscription: s.ID,
+				ELI:          eli,
+				Header:       j.header(act.Year, act.Pos),
+				Title:        act.Title,
+				Summary:      a.Summary,
+				URL:          j.actPageUrl(act.Year, act.Pos),
+				PDF:          j.pdfUrl(act.Year, act.Nr, act.Pos),
+			}
+			for _, t := range s.Notify {
+				id := strings.Join([]string{s.ID, t.String(), eli}, " ")
+				if _, ok := n.deliveries.entries[id]; ok {
+					continue
+				}
+				e := delivery{ID: id, Notification: msg, Target: t}
+				if err := n.deliveries.record(e); err != nil {
+					return err
+				}
+				pending = append(pending, e)
+			}
+		}
+	}
+
+	for _, e := range pending {
+		logger := log.WithField("Subscription", e.Notification.Subscription).WithField("Act", e.Notification.ELI)
+		if err := n.deliver(ctx, e.Target, e.Notification); err != nil {
+			e.Attempts++
+			e.Error = err.Error()
+			e.Done = e.Attempts >= maxPublishAttempts
+			logger.WithError(err).WithField("Attempts", e.Attempts).Error("Could not notify")
+		} else {
+			e.Error = ""
+			e.Done = true
+			logger.Info("Notified")
+		}
+		if err := n.deliveries.record(e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (n *subscribers) deliver(ctx context.Context, t notificationTarget, msg notification) error {
+	if t.Email != "" {
+		if n.email == nil {
+			return errors.New("SMTP is not configured")
+		}
+		return n.email.Notify(ctx, t, msg)
+	}
+	return n.webhook.Notify(ctx, t, msg)
+}
+
+// smtpNotifier sends emails, it is configured with SMTP_ADDR (host:port), SMTP_USERNAME, SMTP_PASSWORD and SMTP_FROM.
+type smtpNotifier struct {
+	addr     string
+	from     string
+	auth     smtp.Auth
+	sendMail func(addr string, a smtp.Auth, from string, to []string, msg []byte) error
+}
+
+func newSMTPFromEnv() *smtpNotifier {
+	addr, from := os.Getenv("SMTP_ADDR"), os.Getenv("SMTP_FROM")
+	if addr == "" || from == "" {
+		return nil
+	}
+	s := &smtpNotifier{addr: addr, from: from, sendMail: smtp.SendMail}
+	if user := os.Getenv("SMTP_USERNAME"); user != "" {
+		host, _, _ := strings.Cut(addr, ":")
+		s.auth = smtp.PlainAuth("", user, os.Getenv("SMTP_PASSWORD"), host)
+	}
+	return s
+}
+
+func (s *smtpNotifier) Notify(_ context.Context, t notificationTarget, n notification) error {
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\n", s.from)
+	fmt.Fprintf(&msg, "To: %s\r\n", t.Email)
+	fmt.Fprintf(&msg, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", n.Header+" "+n.Title))
+	msg.WriteString("MIME-Version: 1.0\r\nContent-Type: text/plain; charset=utf-8\r\nContent-Transfer-Encoding: 8bit\r\n\r\n")
+	msg.WriteString(strings.ReplaceAll(n.text("")+"\n\n"+n.PDF, "\n", "\r\n"))
+	return s.sendMail(s.addr, s.auth, s.from, []string{t.Email}, msg.Bytes())
+}
+
+// webhookNotifier posts JSON to generic, Slack and Discord webhooks.
+type webhookNotifier struct {
+	client *http.Client
+}
+
+func newWebhookNotifier() *webhookNotifier {
+	return &webhookNotifier{client: &http.Client{Timeout: 30 * time.Second}}
+}
+
+func (w *webhookNotifier) Notify(ctx context.Context, t notificationTarget, n notification) error {
+	var url string
+	var payload any
+	switch {
+	case t.Slack != "":
+		url, payload = t.Slack, map[string]string{"text": n.text("*")}
+	case t.Discord != "":
+		url, payload = t.Discord, map[string]string{"content": truncateRunes(n.text("**"), discordMaxLength)}
+	default:
+		url, payload = t.Webhook, n
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	return retry.Do(func() error {
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+		if err != nil {
+			return retry.Unrecoverable(err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+		r, err := w.client.Do(req)
+		if err != nil {
+			return err
+		}
+		defer r.Body.Close()
+		if r.StatusCode >= 300 {
+			data, _ := io.ReadAll(r.Body)
+			err := fmt.Errorf("unexpected status %s: %s", r.Status, data)
+			if r.StatusCode >= 400 && r.StatusCode < 500 && r.StatusCode != http.StatusTooManyRequests {
+				return retry.Unrecoverable(err)
+			}
+			return err
+		}
+		return nil
+	}, retry.Context(ctx), retry.Attempts(3), retry.LastErrorOnly(true), traceRetries(ctx))
+}
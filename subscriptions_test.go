@@ -0,0 +1,298 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/smtp"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func Test_subscriptionMatch(t *testing.T) {
+	t.Parallel()
+	mf := newAct{
+		Title: "Rozporządzenie Ministra Finansów z dnia 1 lutego 2024 r. w sprawie zwolnień od podatku od towarów i usług",
+		Meta:  Act{Type: "Rozporządzenie", Authority: "Minister Finansów"},
+		Text:  "Zwalnia się od podatku dostawę towarów w ramach pomocy de minimis.",
+	}
+	sejm := newAct{Title: "Ustawa z dnia 1 lutego 2024 r. o zmianie ustawy o podatku od nieruchomości"}
+	cba := newAct{Title: "Ustawa z dnia 9 czerwca 2006 r. o Centralnym Biurze Antykorupcyjnym"}
+	tests := []struct {
+		name string
+		sub  subscription
+		act  newAct
+		want bool
+	}{
+		{name: "authority name", sub: subscription{Authorities: []string{"Ministra Finansów"}}, act: mf, want: true},
+		{name: "authority in nominative", sub: subscription{Authorities: []string{"Minister Finansów"}}, act: mf, want: true},
+		{name: "authority handle", sub: subscription{Authorities: []string{"@MF_gov_PL"}}, act: mf, want: true},
+		{name: "other authority", sub: subscription{Authorities: []string{"Ministra Finansów"}}, act: sejm, want: false},
+		{name: "authority in the subject", sub: subscription{Authorities: []string{"@CBAgovPL"}}, act: cba, want: false},
+		{name: "type from metadata", sub: subscription{Types: []string{"rozporządzenie"}}, act: mf, want: true},
+		{name: "type from title", sub: subscription{Types: []string{"Ustawa"}}, act: sejm, want: true},
+		{name: "keyword in text", sub: subscription{Keywords: []string{"de minimis"}}, act: mf, want: true},
+		{name: "inflected keyword", sub: subscription{Keywords: []string{"podatek od nieruchomosci"}}, act: sejm, want: true},
+		{name: "missing keyword", sub: subscription{Keywords: []string{"akcyza", "cło"}}, act: mf, want: false},
+		{name: "regex", sub: subscription{Regex: `towarów i usług`}, act: mf, want: true},
+		{name: "all criteria", sub: subscription{Types: []string{"Ustawa"}, Keywords: []string{"de minimis"}}, act: mf, want: false},
+		{name: "journal", sub: subscription{Journals: []string{"MP"}}, act: mf, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sub := tt.sub
+			sub.ID, sub.Notify = "test", []notificationTarget{{Webhook: "http://localhost"}}
+			if err := sub.validate(); err != nil {
+				t.Fatal(err)
+			}
+			if got := sub.match(&subscribedAct{Journal: dziennikUstaw, Act: tt.act}); got != tt.want {
+				t.Errorf("match() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_loadSubscriptions(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	if subs, err := loadSubscriptions(filepath.Join(dir, subscriptionsFile)); err != nil || subs != nil {
+		t.Errorf("missing file = %v, %v", subs, err)
+	}
+	for _, config := range []string{
+		`[{"notify": [{"webhook": "http://localhost"}]}]`,
+		`[{"id": "a"}]`,
+		`[{"id": "a", "notify": [{}]}]`,
+		`[{"id": "a", "authorities": ["Ministra Czegoś"], "notify": [{"webhook": "http://localhost"}]}]`,
+		`[{"id": "a", "regex": "(", "notify": [{"webhook": "http://localhost"}]}]`,
+		`[{"id": "a", "journals": ["XX"], "notify": [{"webhook": "http://localhost"}]}]`,
+		`[{"id": "a", "notify": [{"webhook": "http://localhost"}]}, {"id": "a", "notify": [{"webhook": "http://localhost"}]}]`,
+	} {
+		path := filepath.Join(dir, "invalid.json")
+		if err := os.WriteFile(path, []byte(config), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := loadSubscriptions(path); err == nil {
+			t.Errorf("loadSubscriptions(%s) returned no error", config)
+		}
+	}
+}
+
+type fakeSMTP struct {
+	mu   sync.Mutex
+	sent []string
+}
+
+func (f *fakeSMTP) send(_ string, _ smtp.Auth, _ string, to []string, msg []byte) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.sent = append(f.sent, strings.Join(to, ",")+"\n"+string(msg))
+	return nil
+}
+
+func Test_subscribers(t *testing.T) {
+	t.Parallel()
+	var mu sync.Mutex
+	received := map[string][]string{}
+	failures := 1
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		defer mu.Unlock()
+		if r.URL.Path == "/flaky" && failures > 0 {
+			failures--
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		received[r.URL.Path] = append(received[r.URL.Path], string(body))
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	config := `[
+		{"id": "mf", "authorities": ["@MF_gov_PL"], "notify": [{"email": "compliance@example.com"}, {"slack": "` + server.URL + `/slack"}]},
+		{"id": "podatki", "keywords": ["podatek"], "notify": [{"discord": "` + server.URL + `/discord"}, {"webhook": "` + server.URL + `/flaky"}]}
+	]`
+	path := filepath.Join(dir, subscriptionsFile)
+	if err := os.WriteFile(path, []byte(config), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	subs, err := loadSubscriptions(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	deliveries, err := openDeliveryLog(filepath.Join(dir, deliveriesFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	mail := &fakeSMTP{}
+	s := &subscribers{
+		subscriptions: subs,
+		deliveries:    deliveries,
+		email:         &smtpNotifier{addr: "smtp:25", from: "bot@example.com", sendMail: mail.send},
+		webhook:       newWebhookNotifier(),
+	}
+	acts := []*subscribedAct{
+		{Journal: dziennikUstaw, Act: newAct{Year: 2024, Pos: 1, Title: "Rozporządzenie Ministra Finansów w sprawie podatku akcyzowego"}, Summary: "Akcyza"},
+		{Journal: dziennikUstaw, Act: newAct{Year: 2024, Pos: 2, Title: "Ustawa o drogach publicznych"}},
+	}
+	if err := s.run(context.Background(), acts); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(mail.sent) != 1 || !strings.HasPrefix(mail.sent[0], "compliance@example.com\n") || !strings.Contains(mail.sent[0], "Subject: =?utf-8?q?Dz.U._2024_poz._1_Rozporz=C4=85dzenie") ||
+		!strings.Contains(mail.sent[0], "\r\n\r\nDz.U. 2024 poz. 1\r\nRozporządzenie Ministra Finansów w sprawie podatku akcyzowego\r\nAkcyza\r\n") {
+		t.Errorf("emails = %q", mail.sent)
+	}
+	if len(received["/slack"]) != 1 || !strings.Contains(received["/slack"][0], `"text":"*Dz.U. 2024 poz. 1*\nRozporządzenie`) {
+		t.Errorf("slack = %q", received["/slack"])
+	}
+	if len(received["/discord"]) != 1 || !strings.Contains(received["/discord"][0], `"content":"**Dz.U. 2024 poz. 1**`) {
+		t.Errorf("discord = %q", received["/discord"])
+	}
+	if len(received["/flaky"]) != 0 {
+		t.Errorf("webhook = %q", received["/flaky"])
+	}
+
+	// the next run retries the failed webhook and does not notify anybody twice
+	deliveries, err = openDeliveryLog(filepath.Join(dir, deliveriesFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.deliveries = deliveries
+	if err := s.run(context.Background(), acts); err != nil {
+		t.Fatal(err)
+	}
+	if len(mail.sent) != 1 || len(received["/slack"]) != 1 || len(received["/discord"]) != 1 {
+		t.Errorf("notified twice: emails %d, slack %d, discord %d", len(mail.sent), len(received["/slack"]), len(received["/discord"]))
+	}
+	if len(received["/flaky"]) != 1 {
+		t.Fatalf("webhook = %q", received["/flaky"])
+	}
+	var n notification
+	if err := json.Unmarshal([]byte(received["/flaky"][0]), &n); err != nil {
+		t.Fatal(err)
+	}
+	if n.Subscription != "podatki" || n.ELI != "DU/2024/1" || n.Summary != "Akcyza" || n.PDF != "https://dziennikustaw.gov.pl/D2024000000101.pdf" {
+		t.Errorf("webhook payload = %+v", n)
+	}
+	for id, e := range s.deliveries.entries {
+		if !e.Done || e.Error != "" {
+			t.Errorf("delivery %s = %+v", id, e)
+		}
+	}
+}
+
+func Test_publishedActs(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	du := *dziennikUstaw
+	du.Ledger, du.Texts = filepath.Join(dir, ledgerFile), filepath.Join(dir, "text")
+	l, err := du.openLedger()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range []ledgerEntry{
+		{Year: 2024, Pos: 1, Status: statusPublished},
+		{Year: 2024, Pos: 3, Title: "Ustawa o podatku", Summary: "Podatek", Status: statusPublished},
+		{Year: 2024, Pos: 2, Title: "Ustawa o drogach", Status: statusPublished},
+		{Year: 2024, Pos: 4, Title: "Ustawa o gminach", Status: statusPending},
+		{Year: 2024, Pos: 5, Status: statusMissing},
+	} {
+		if err := l.record(e); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := du.saveText(2024, 2, "Art. 1. Drogi publiczne"); err != nil {
+		t.Fatal(err)
+	}
+	acts := publishedActs(l, time.Now().Add(-time.Hour))
+	var got []string
+	for _, a := range acts {
+		got = append(got, fmt.Sprintf("%d %s %q %q", a.Act.Pos, a.Act.Title, a.Summary, a.Act.Text))
+	}
+	want := []string{`2 Ustawa o drogach "" "Art. 1. Drogi publiczne"`, `3 Ustawa o podatku "Podatek" ""`}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("publishedActs() = %q, want %q", got, want)
+	}
+	if acts := publishedActs(l, time.Now().Add(time.Hour)); len(acts) != 0 {
+		t.Errorf("publishedActs() in the future = %d acts", len(acts))
+	}
+
+	// acts published before a subscription was added are not notified
+	s := subscription{ID: "all", Created: time.Now().Add(time.Hour)}
+	if s.match(acts[0]) {
+		t.Error("act published before the subscription matched")
+	}
+	s.Created = time.Time{}
+	if !s.match(acts[0]) {
+		t.Error("act did not match a subscription without criteria")
+	}
+}
+
+func Test_subscribersWithoutSMTP(t *testing.T) {
+	t.Parallel()
+	s := &subscribers{}
+	if err := s.deliver(context.Background(), notificationTarget{Email: "a@example.com"}, notification{}); err == nil || !strings.Contains(err.Error(), "SMTP") {
+		t.Errorf("deliver() = %v", err)
+	}
+}
+
+func Test_subscriptionsCommand(t *testing.T) {
+	t.Parallel()
+	path := filepath.Join(t.TempDir(), subscriptionsFile)
+	s, err := parseSubscription([]string{"-id", "mf", "-authority", "Ministra Finansów, Funduszy i Polityki Regionalnej", "-authority", "@MF_gov_PL",
+		"-type", "Rozporządzenie", "-email", "a@example.com", "-slack", "https://hooks.slack.com/services/x"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := addSubscription(path, s); err != nil {
+		t.Fatal(err)
+	}
+	if err := addSubscription(path, s); err == nil {
+		t.Error("subscription with the same id was added")
+	}
+	if err := addSubscription(path, subscription{ID: "none"}); err == nil {
+		t.Error("subscription without targets was added")
+	}
+	if err := addSubscription(path, subscription{ID: "all", Notify: []notificationTarget{{Webhook: "https://example.com/acts"}}}); err != nil {
+		t.Fatal(err)
+	}
+
+	subs, err := loadSubscriptions(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(subs) != 2 || subs[0].Created.IsZero() || len(subs[0].Authorities) != 2 {
+		t.Fatalf("subscriptions = %+v", subs)
+	}
+	var out strings.Builder
+	if err := listSubscriptions(&out, subs); err != nil {
+		t.Fatal(err)
+	}
+	want := "mf\tauthorities: Ministra Finansów, Funduszy i Polityki Regionalnej, @MF_gov_PL; types: Rozporządzenie\t-> email:a@example.com, slack:https://hooks.slack.com/services/x\n" +
+		"all\tall acts\t-> webhook:https://example.com/acts\n"
+	if out.String() != want {
+		t.Errorf("list =\n%s\nwant\n%s", out.String(), want)
+	}
+
+	if err := removeSubscription(path, "mf"); err != nil {
+		t.Fatal(err)
+	}
+	if err := removeSubscription(path, "mf"); err == nil {
+		t.Error("removing a missing subscription returned no error")
+	}
+	if subs, _ := loadSubscriptions(path); len(subs) != 1 || subs[0].ID != "all" {
+		t.Errorf("subscriptions after remove = %+v", subs)
+	}
+	if _, err := parseSubscription([]string{"-id", "x", "extra"}); err == nil {
+		t.Error("parseSubscription() accepted extra arguments")
+	}
+}
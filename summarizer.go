@@ -0,0 +1,234 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/openai/openai-go/v2"
+	"github.com/openai/openai-go/v2/option"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	providerOpenAI           = "openai"
+	providerOpenAICompatible = "openai-compatible"
+	providerFake             = "fake"
+
+	defaultSummarizerModel = openai.ChatModelGPT5Nano
+	// defaultInputTokens is the input limit of OpenAI models, local models usually have much smaller context.
+	defaultInputTokens           = 270000
+	defaultCompatibleInputTokens = 8192
+)
+
+type chatMessage struct {
+	// Role is system, user or assistant.
+	Role    string
+	Content string
+}
+
+// summarizer is a chat model used to summarize acts.
+type summarizer interface {
+	// Complete returns the model reply to the conversation.
+	Complete(ctx context.Context, messages []chatMessage) (string, error)
+	// Tokens returns the number of tokens the model needs for the text.
+	Tokens(text string) int
+	// InputTokens returns the maximum number of tokens the model accepts as an input.
+	InputTokens() int
+}
+
+type summarizerConfig struct {
+	Provider string
+	Model    string
+	// BaseURL of an OpenAI compatible API, e.g. http://localhost:11434/v1 for Ollama.
+	BaseURL     string
+	APIKey      string
+	Temperature *float64
+	MaxTokens   int
+	InputTokens int
+}
+
+// llm is the summarizer configured with SUMMARIZER_* environment variables.
+var llm = sync.OnceValues(func() (summarizer, error) {
+	cfg, err := summarizerConfigFromEnv()
+	if err != nil {
+		return nil, fmt.Errorf("could not configure summarizer: %w", err)
+	}
+	s, err := newSummarizer(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("could not configure summarizer: %w", err)
+	}
+	return s, nil
+})
+
+func summarizerConfigFromEnv() (summarizerConfig, error) {
+	cfg := summarizerConfig{
+		Provider: os.Getenv("SUMMARIZER"),
+		Model:    os.Getenv("SUMMARIZER_MODEL"),
+		BaseURL:  os.Getenv("SUMMARIZER_URL"),
+		APIKey:   os.Getenv("SUMMARIZER_API_KEY"),
+	}
+	if v := os.Getenv("SUMMARIZER_TEMPERATURE"); v != "" {
+		t, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return cfg, fmt.Errorf("invalid SUMMARIZER_TEMPERATURE: %w", err)
+		}
+		cfg.Temperature = &t
+	}
+	if v := os.Getenv("SUMMARIZER_MAX_TOKENS"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return cfg, fmt.Errorf("invalid SUMMARIZER_MAX_TOKENS: %w", err)
+		}
+		cfg.MaxTokens = n
+	}
+	if v := os.Getenv("SUMMARIZER_INPUT_TOKENS"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return cfg, fmt.Errorf("invalid SUMMARIZER_INPUT_TOKENS: %w", err)
+		}
+		cfg.InputTokens = n
+	}
+	return cfg, nil
+}
+
+func newSummarizer(cfg summarizerConfig) (summarizer, error) {
+	switch cfg.Provider {
+	case "", providerOpenAI:
+		opts := []option.RequestOption{option.WithHTTPClient(&http.Client{Transport: tracedTransport(nil)})}
+		if cfg.APIKey != "" {
+			opts = append(opts, option.WithAPIKey(cfg.APIKey))
+		}
+		if cfg.BaseURL != "" {
+			opts = append(opts, option.WithBaseURL(cfg.BaseURL))
+		}
+		if cfg.Model == "" {
+			cfg.Model = defaultSummarizerModel
+		}
+		if cfg.InputTokens == 0 {
+			cfg.InputTokens = defaultInputTokens
+		}
+		return &openaiSummarizer{client: openai.NewClient(opts...), config: cfg, tokens: tiktokenTokens}, nil
+	case providerOpenAICompatible:
+		if cfg.BaseURL == "" {
+			return nil, errors.New("SUMMARIZER_URL is required for OpenAI compatible summarizer")
+		}
+		if cfg.Model == "" {
+			return nil, errors.New("SUMMARIZER_MODEL is required for OpenAI compatible summarizer")
+		}
+		if cfg.InputTokens == 0 {
+			cfg.InputTokens = defaultCompatibleInputTokens
+		}
+		// local servers usually do not check the key but the client refuses to work without one
+		key := cfg.APIKey
+		if key == "" {
+			key = "none"
+		}
+		client := openai.NewClient(option.WithBaseURL(cfg.BaseURL), option.WithAPIKey(key), option.WithHTTPClient(&http.Client{Transport: tracedTransport(nil)}))
+		// local models have their own tokenizers, an estimate is good enough and works offline
+		return &openaiSummarizer{client: client, config: cfg, tokens: estimateTokens}, nil
+	case providerFake:
+		return fakeSummarizer{inputTokens: cfg.InputTokens}, nil
+	}
+	return nil, fmt.Errorf("unknown summarizer %q", cfg.Provider)
+}
+
+type openaiSummarizer struct {
+	client openai.Client
+	config summarizerConfig
+	tokens func(string) int
+}
+
+func (o *openaiSummarizer) Complete(ctx context.Context, messages []chatMessage) (string, error) {
+	params := openai.ChatCompletionNewParams{
+		Model: o.config.Model,
+	}
+	for _, m := range messages {
+		switch m.Role {
+		case "system":
+			params.Messages = append(params.Messages, openai.SystemMessage(m.Content))
+		case "assistant":
+			params.Messages = append(params.Messages, openai.AssistantMessage(m.Content))
+		default:
+			params.Messages = append(params.Messages, openai.UserMessage(m.Content))
+		}
+	}
+	if o.config.Temperature != nil {
+		params.Temperature = openai.Float(*o.config.Temperature)
+	}
+	if o.config.MaxTokens > 0 {
+		if o.config.Provider == providerOpenAICompatible {
+			// max_completion_tokens is not supported by all compatible servers
+			params.MaxTokens = openai.Int(int64(o.config.MaxTokens))
+		} else {
+			params.MaxCompletionTokens = openai.Int(int64(o.config.MaxTokens))
+		}
+	}
+	completion, err := o.client.Chat.Completions.New(ctx, params)
+	if err != nil {
+		return "", err
+	}
+	openaiTokens.WithLabelValues(o.config.Model, "prompt").Add(float64(completion.Usage.PromptTokens))
+	openaiTokens.WithLabelValues(o.config.Model, "completion").Add(float64(completion.Usage.CompletionTokens))
+	if len(completion.Choices) == 0 {
+		return "", errors.New("no completion choices")
+	}
+	return completion.Choices[0].Message.Content, nil
+}
+
+func (o *openaiSummarizer) Tokens(text string) int {
+	return o.tokens(text)
+}
+
+func (o *openaiSummarizer) InputTokens() int {
+	return o.config.InputTokens
+}
+
+// tiktokenTokens counts tokens with the OpenAI tokenizer, the text is estimated when the tokenizer could not be loaded.
+func tiktokenTokens(text string) int {
+	n, err := countTokens(text)
+	if err != nil {
+		log.WithError(err).Warn("Could not count tokens, estimating")
+		return estimateTokens(text)
+	}
+	return n
+}
+
+// estimateTokens assumes 4 characters per token which is typical for BPE tokenizers.
+func estimateTokens(text string) int {
+	return (utf8.RuneCountInString(text) + 3) / 4
+}
+
+// fakeSummarizer returns the beginning of the act text so the pipeline can run offline.
+type fakeSummarizer struct {
+	inputTokens int
+}
+
+func (fakeSummarizer) Complete(_ context.Context, messages []chatMessage) (string, error) {
+	var text string
+	for _, m := range messages {
+		if m.Role == "user" {
+			text = m.Content
+			break
+		}
+	}
+	text = strings.Join(strings.Fields(text), " ")
+	return "Streszczenie: " + truncateRunes(text, 120), nil
+}
+
+func (fakeSummarizer) Tokens(text string) int {
+	return estimateTokens(text)
+}
+
+func (f fakeSummarizer) InputTokens() int {
+	if f.inputTokens == 0 {
+		return defaultInputTokens
+	}
+	return f.inputTokens
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+type fakeChatServer struct {
+	mu       sync.Mutex
+	requests []map[string]any
+	replies  []string
+}
+
+func newFakeChatServer(t *testing.T, replies ...string) (*fakeChatServer, *httptest.Server) {
+	f := &fakeChatServer{replies: replies}
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /v1/chat/completions", func(w http.ResponseWriter, r *http.Request) {
+		var req map[string]any
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		f.mu.Lock()
+		f.requests = append(f.requests, req)
+		reply := f.replies[0]
+		if len(f.replies) > 1 {
+			f.replies = f.replies[1:]
+		}
+		f.mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		content, _ := json.Marshal(reply)
+		fmt.Fprintf(w, `{"id":"c1","object":"chat.completion","created":0,"model":%q,"choices":[{"index":0,"finish_reason":"stop","message":{"role":"assistant","content":%s}}]}`, req["model"], content)
+	})
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return f, srv
+}
+
+func Test_openAICompatibleSummarizer(t *testing.T) {
+	t.Parallel()
+	f, srv := newFakeChatServer(t, strings.Repeat("za długie ", 30), "Krótkie podsumowanie")
+	temperature := 0.2
+	s, err := newSummarizer(summarizerConfig{
+		Provider:    providerOpenAICompatible,
+		BaseURL:     srv.URL + "/v1",
+		Model:       "llama3.1:8b",
+		Temperature: &temperature,
+		MaxTokens:   200,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	summary, err := summarize(context.Background(), s, "Art. 1. Ustawa wchodzi w życie z dniem ogłoszenia.")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if summary != "Krótkie podsumowanie" {
+		t.Errorf("summarize() = %q", summary)
+	}
+	if len(f.requests) != 2 {
+		t.Fatalf("expected 2 requests got %d", len(f.requests))
+	}
+	req := f.requests[1]
+	if req["model"] != "llama3.1:8b" || req["temperature"] != 0.2 || req["max_tokens"] != 200.0 {
+		t.Errorf("request = %v", req)
+	}
+	// the too long reply and feedback are sent back to the model
+	if messages := req["messages"].([]any); len(messages) != 4 || messages[2].(map[string]any)["role"] != "assistant" {
+		t.Errorf("messages = %v", messages)
+	}
+}
+
+func Test_fakeSummarizer(t *testing.T) {
+	t.Parallel()
+	summary, err := summarize(context.Background(), fakeSummarizer{}, "Art. 1.\nUstawa określa zasady\nwydawania aktów.")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if summary != "Streszczenie: Art. 1. Ustawa określa zasady wydawania aktów." {
+		t.Errorf("summarize() = %q", summary)
+	}
+	long, err := summarize(context.Background(), fakeSummarizer{}, strings.Repeat("żółć ", 200))
+	if err != nil || len(long) >= 280 {
+		t.Errorf("summarize() = %q, %v", long, err)
+	}
+}
+
+func Test_newSummarizer(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		cfg     summarizerConfig
+		wantErr bool
+	}{
+		{cfg: summarizerConfig{}},
+		{cfg: summarizerConfig{Provider: providerFake}},
+		{cfg: summarizerConfig{Provider: providerOpenAICompatible, BaseURL: "http://localhost:11434/v1", Model: "llama3.1"}},
+		{cfg: summarizerConfig{Provider: providerOpenAICompatible, Model: "llama3.1"}, wantErr: true},
+		{cfg: summarizerConfig{Provider: providerOpenAICompatible, BaseURL: "http://localhost:11434/v1"}, wantErr: true},
+		{cfg: summarizerConfig{Provider: "claude"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		if _, err := newSummarizer(tt.cfg); (err != nil) != tt.wantErr {
+			t.Errorf("newSummarizer(%+v) error = %v, wantErr %v", tt.cfg, err, tt.wantErr)
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+
+	"github.com/avast/retry-go"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.41.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	tracerName  = "github.com/janisz/DU"
+	serviceName = "dziennik-ustaw"
+)
+
+// setupTracing configures the exporter chosen with OTEL_TRACES_EXPORTER: otlp sends spans to
+// OTEL_EXPORTER_OTLP_ENDPOINT, e.g. a local Jaeger, console writes them as JSON to stdout or to
+// OTEL_TRACES_FILE. Tracing is disabled by default. shutdown flushes spans not exported yet.
+func setupTracing(ctx context.Context) (shutdown func(context.Context) error, err error) {
+	var exporter sdktrace.SpanExporter
+	var file *os.File
+	switch name := os.Getenv("OTEL_TRACES_EXPORTER"); name {
+	case "", "none":
+		return func(context.Context) error { return nil }, nil
+	case "otlp":
+		exporter, err = otlptracehttp.New(ctx)
+	case "console":
+		var w io.Writer = os.Stdout
+		if path := os.Getenv("OTEL_TRACES_FILE"); path != "" {
+			file, err = os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+			if err != nil {
+				return nil, err
+			}
+			w = file
+		}
+		exporter, err = stdouttrace.New(stdouttrace.WithWriter(w))
+	default:
+		return nil, fmt.Errorf("unknown OTEL_TRACES_EXPORTER %q, expected otlp, console or none", name)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not create %s exporter: %w", os.Getenv("OTEL_TRACES_EXPORTER"), err)
+	}
+	// OTEL_SERVICE_NAME and OTEL_RESOURCE_ATTRIBUTES override the defaults
+	res, err := resource.New(ctx, resource.WithAttributes(semconv.ServiceName(serviceName)), resource.WithFromEnv(), resource.WithTelemetrySDK())
+	if err != nil {
+		return nil, err
+	}
+	provider := sdktrace.NewTracerProvider(sdktrace.WithBatcher(exporter), sdktrace.WithResource(res))
+	otel.SetTracerProvider(provider)
+	return func(ctx context.Context) error {
+		err := provider.Shutdown(ctx)
+		if file != nil {
+			err = errors.Join(err, file.Close())
+		}
+		return err
+	}, nil
+}
+
+// startSpan starts a span of the pipeline, spans are not recorded unless tracing is set up.
+func startSpan(ctx context.Context, name string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	return otel.Tracer(tracerName).Start(ctx, name, opts...)
+}
+
+// endSpan ends the span marking it failed when err is not nil.
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// actAttributes identify the act in spans.
+func actAttributes(code string, year, pos int) trace.SpanStartEventOption {
+	return trace.WithAttributes(
+		attribute.String("act.eli", fmt.Sprintf("%s/%d/%d", code, year, pos)),
+		attribute.String("act.journal", code),
+		attribute.Int("act.year", year),
+		attribute.Int("act.pos", pos),
+	)
+}
+
+// retryEvent records a failed attempt as an event of the span in ctx.
+func retryEvent(ctx context.Context, n uint, err error) {
+	trace.SpanFromContext(ctx).AddEvent("retry", trace.WithAttributes(
+		attribute.Int("retry.attempt", int(n)+1),
+		attribute.String("retry.error", err.Error()),
+	))
+}
+
+// traceRetries records failed attempts of retry.Do as span events.
+func traceRetries(ctx context.Context) retry.Option {
+	return retry.OnRetry(func(n uint, err error) {
+		retryEvent(ctx, n, err)
+	})
+}
+
+// tracedTransport creates a span for every HTTP request made with a traced context.
+func tracedTransport(base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return otelhttp.NewTransport(base)
+}
+
+// inSpan runs a stage of the pipeline in its own span.
+func inSpan[T any](ctx context.Context, name string, stage func(context.Context) (T, error)) (T, error) {
+	ctx, span := startSpan(ctx, name)
+	result, err := stage(ctx)
+	endSpan(span, err)
+	return result, err
+}
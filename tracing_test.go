@@ -0,0 +1,114 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+// recordSpans records spans of the test, it must not run in parallel as the tracer provider is global.
+func recordSpans(t *testing.T) *tracetest.InMemoryExporter {
+	t.Helper()
+	exporter := tracetest.NewInMemoryExporter()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter)))
+	t.Cleanup(func() { otel.SetTracerProvider(noop.NewTracerProvider()) })
+	return exporter
+}
+
+func spanNames(spans tracetest.SpanStubs, traceID trace.TraceID) string {
+	var names []string
+	for _, s := range spans {
+		if s.SpanContext.TraceID() == traceID && s.SpanKind != trace.SpanKindClient {
+			names = append(names, s.Name)
+		}
+	}
+	return strings.Join(names, ",")
+}
+
+func Test_traceAct(t *testing.T) {
+	exporter := recordSpans(t)
+	l, err := openLedger(filepath.Join(t.TempDir(), ledgerFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	year := time.Now().Year()
+	if err := l.record(ledgerEntry{Year: year - 1, Pos: 2000, Status: statusPublished}); err != nil {
+		t.Fatal(err)
+	}
+	_, j := newFakeSite(t, dziennikUstaw, map[actKey]string{{year, 1}: "Rozporządzenie Ministra Zdrowia z dnia 2 stycznia 2026 r."})
+	j.site.client = &http.Client{Transport: tracedTransport(j.site.client.Transport)}
+
+	acts, _, err := prepareNewActs(context.Background(), l, &discovery{journal: j, window: 1})
+	if err != nil || len(acts) != 1 {
+		t.Fatalf("prepareNewActs() = %d acts, %v", len(acts), err)
+	}
+	act := acts[0]
+	act.Summary = func() (string, error) { return "Podsumowanie", nil }
+	publishAct(context.Background(), l, act, []publisher{&fakePublisher{name: "ok"}})
+
+	spans := exporter.GetSpans()
+	var root, discover tracetest.SpanStub
+	clients := 0
+	for _, s := range spans {
+		switch {
+		case s.Name == "act":
+			root = s
+		case s.Name == "discover":
+			discover = s
+		case s.SpanKind == trace.SpanKindClient && s.SpanContext.TraceID() == act.Trace.TraceID():
+			clients++
+		}
+	}
+	if root.Parent.IsValid() || root.SpanContext.TraceID() != act.Trace.TraceID() {
+		t.Fatalf("act span = %+v, act trace %s", root, act.Trace.TraceID())
+	}
+	if discover.SpanContext.TraceID() == root.SpanContext.TraceID() {
+		t.Errorf("discovery is traced with the act")
+	}
+	want := "download pdf,render,extract text,fetch,act,upload media,announce,reply,publish to ok,publish"
+	if got := spanNames(spans, root.SpanContext.TraceID()); got != want {
+		t.Errorf("act spans = %s, want %s", got, want)
+	}
+	// only the PDF, the act page was downloaded during discovery
+	if clients != 1 {
+		t.Errorf("HTTP spans = %d, want 1", clients)
+	}
+}
+
+func Test_traceSummaryRetries(t *testing.T) {
+	exporter := recordSpans(t)
+	_, srv := newFakeChatServer(t, strings.Repeat("za długie ", 30), "Krótkie podsumowanie")
+	s, err := newSummarizer(summarizerConfig{Provider: providerOpenAICompatible, BaseURL: srv.URL + "/v1", Model: "llama3.1:8b"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := summarize(context.Background(), s, "Art. 1. Ustawa wchodzi w życie z dniem ogłoszenia."); err != nil {
+		t.Fatal(err)
+	}
+
+	var summary tracetest.SpanStub
+	clients := 0
+	for _, span := range exporter.GetSpans() {
+		if span.Name == "summarize" {
+			summary = span
+		}
+		if span.SpanKind == trace.SpanKindClient {
+			clients++
+		}
+	}
+	if len(summary.Events) != 1 || summary.Events[0].Name != "retry" {
+		t.Errorf("summarize events = %+v", summary.Events)
+	}
+	if clients != 2 {
+		t.Errorf("HTTP spans = %d, want 2", clients)
+	}
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+)
+
+const (
+	twitterMaxLength = 280
+	// twitterURLLength is the length of every URL after t.co shortening.
+	twitterURLLength = 23
+)
+
+// twitterLightRanges are code points counted as a single character, everything else counts as two.
+// See https://github.com/twitter/twitter-text/blob/master/config/v3.json
+var twitterLightRanges = [][2]rune{
+	{0, 4351},
+	{8192, 8205},
+	{8208, 8223},
+	{8242, 8247},
+}
+
+// tweetURLRegexp matches URLs with a scheme and bare domains with common TLDs, Twitter links both.
+var tweetURLRegexp = regexp.MustCompile(`(?i)\bhttps?://[^\s<>"]+|\b(?:[a-z0-9-]+\.)+(?:pl|eu|com|org|net|gov|edu|info|io)\b(?:/[^\s<>"]*)?`)
+
+// tweetLength returns the length of the text as counted by Twitter (twitter-text v3): URLs count as 23
+// characters, emoji sequences and most code points outside Latin scripts count as 2.
+func tweetLength(text string) int {
+	length := 0
+	offset := 0
+	for _, loc := range tweetURLRegexp.FindAllStringIndex(text, -1) {
+		// trailing punctuation is not a part of the link
+		end := loc[0] + len(strings.TrimRight(text[loc[0]:loc[1]], ".,:;!?'\")"))
+		length += weightedLength(text[offset:loc[0]]) + twitterURLLength
+		offset = end
+	}
+	return length + weightedLength(text[offset:])
+}
+
+func weightedLength(text string) int {
+	runes := []rune(text)
+	length := 0
+	for i := 0; i < len(runes); {
+		if n := emojiSequenceLength(runes[i:]); n > 0 {
+			length += 2
+			i += n
+			continue
+		}
+		length += runeWeight(runes[i])
+		i++
+	}
+	return length
+}
+
+func runeWeight(r rune) int {
+	for _, lr := range twitterLightRanges {
+		if r >= lr[0] && r <= lr[1] {
+			return 1
+		}
+	}
+	return 2
+}
+
+const (
+	zeroWidthJoiner   = '\u200d'
+	variationSelector = '\ufe0f'
+	combiningKeycap   = '\u20e3'
+)
+
+// emojiSequenceLength returns how many runes form the emoji at the beginning, 0 if there is no emoji.
+func emojiSequenceLength(runes []rune) int {
+	at := func(i int) rune {
+		if i < len(runes) {
+			return runes[i]
+		}
+		return -1
+	}
+	r := runes[0]
+	switch {
+	case isRegionalIndicator(r):
+		if isRegionalIndicator(at(1)) {
+			return 2
+		}
+		return 1
+	case r >= '0' && r <= '9' || r == '#' || r == '*':
+		if at(1) == variationSelector && at(2) == combiningKeycap {
+			return 3
+		}
+		if at(1) == combiningKeycap {
+			return 2
+		}
+		return 0
+	case !isEmoji(r) && !(at(1) == variationSelector && isTextEmoji(r)):
+		// the variation selector makes an emoji only of symbols that have an emoji form
+		return 0
+	}
+	n := 1
+	for {
+		c := at(n)
+		switch {
+		case c == variationSelector, c == combiningKeycap, isSkinTone(c), c >= 0xe0020 && c <= 0xe007f:
+			n++
+		case c == zeroWidthJoiner && isEmoji(at(n+1)):
+			n += 2
+		default:
+			return n
+		}
+	}
+}
+
+func isEmoji(r rune) bool {
+	return r >= 0x1f000 && r <= 0x1faff ||
+		r >= 0x2600 && r <= 0x27bf ||
+		r >= 0x2300 && r <= 0x23ff ||
+		r >= 0x2b00 && r <= 0x2bff
+}
+
+// isTextEmoji reports whether r is shown as text by default and as an emoji with the variation selector,
+// e.g. © or ↔. Symbols in the emoji blocks are covered by isEmoji.
+func isTextEmoji(r rune) bool {
+	switch r {
+	case 0xa9, 0xae, 0x203c, 0x2049, 0x2122, 0x2139, 0x21a9, 0x21aa, 0x24c2, 0x25b6, 0x25c0, 0x2934, 0x2935, 0x3030, 0x303d, 0x3297, 0x3299:
+		return true
+	}
+	return r >= 0x2194 && r <= 0x2199 || r >= 0x25aa && r <= 0x25ab || r >= 0x25fb && r <= 0x25fe
+}
+
+func isRegionalIndicator(r rune) bool {
+	return r >= 0x1f1e6 && r <= 0x1f1ff
+}
+
+func isSkinTone(r rune) bool {
+	return r >= 0x1f3fb && r <= 0x1f3ff
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func Test_tweetLength(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name string
+		text string
+		want int
+	}{
+		{name: "empty", text: "", want: 0},
+		{name: "ascii", text: "Dz.U. 2020 poz. 2", want: 17},
+		{name: "polish diacritics", text: "zażółć gęślą jaźń", want: 17},
+		{name: "new lines", text: "a\nb\n", want: 4},
+		{name: "cyrillic", text: "Україна", want: 7},
+		{name: "cjk", text: "日本語", want: 6},
+		{name: "hyphen in light range", text: "‐", want: 1},
+		{name: "em dash in light range", text: "—", want: 1},
+		{name: "prime in light range", text: "′", want: 1},
+		{name: "ellipsis", text: "…", want: 2},
+		{name: "euro sign", text: "€", want: 2},
+		{name: "section sign", text: "§ 1", want: 3},
+		{name: "emoji", text: "📢", want: 2},
+		{name: "emoji with text", text: "📢Obwieszczenie", want: 15},
+		{name: "symbol emoji", text: "⚖", want: 2},
+		{name: "emoji presentation", text: "❤️", want: 2},
+		{name: "text symbol with variation selector", text: "©️", want: 2},
+		{name: "text symbol", text: "©", want: 1},
+		{name: "arrow with variation selector", text: "↔️", want: 2},
+		{name: "letter with variation selector", text: "a\ufe0f", want: 3},
+		{name: "section sign with variation selector", text: "§\ufe0f 1", want: 5},
+		{name: "skin tone", text: "👍🏽", want: 2},
+		{name: "zwj family", text: "👨‍👩‍👧‍👦", want: 2},
+		{name: "zwj profession", text: "👩🏽‍⚖️", want: 2},
+		{name: "flag", text: "🇵🇱", want: 2},
+		{name: "two flags", text: "🇵🇱🇪🇺", want: 4},
+		{name: "lonely regional indicator", text: "🇵", want: 2},
+		{name: "tag sequence", text: "🏴󠁧󠁢󠁳󠁣󠁴󠁿", want: 2},
+		{name: "keycap", text: "1️⃣", want: 2},
+		{name: "keycap without selector", text: "#⃣", want: 2},
+		{name: "digits", text: "2026", want: 4},
+		{name: "url", text: "https://dziennikustaw.gov.pl/D2020000000201.pdf", want: 23},
+		{name: "short url", text: "http://a.pl", want: 23},
+		{name: "url in text", text: "Zobacz https://isap.sejm.gov.pl/isap.nsf/home.xsp teraz", want: 7 + 23 + 6},
+		{name: "url with trailing dot", text: "Więcej na https://www.gov.pl.", want: 10 + 23 + 1},
+		{name: "bare domain", text: "gov.pl", want: 23},
+		{name: "bare domain with path", text: "Szczegóły: www.gov.pl/web/finanse)", want: 11 + 23 + 1},
+		{name: "two urls", text: "http://a.pl http://b.pl", want: 47},
+		{name: "abbreviations are not urls", text: "Dz.U. M.P. r. poz. ust. pkt.", want: 28},
+		{name: "position is not a url", text: "poz.123", want: 7},
+		{name: "article is not a url", text: "art.5", want: 5},
+		{name: "unknown tld is not a url", text: "ustawa.zmiana", want: 13},
+		{name: "handle", text: "@MZ_GOV_PL", want: 10},
+		{name: "hashtag", text: "#PIT", want: 4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			if got := tweetLength(tt.text); got != tt.want {
+				t.Errorf("tweetLength(%q) = %d, want %d", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_shortenTitleWeighted(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name  string
+		title string
+		max   int
+		want  string
+	}{
+		{name: "fits", title: "Ustawa o podatku", max: 16, want: "Ustawa o podatku"},
+		{name: "ellipsis counts as two", title: "Ustawa o podatku", max: 15, want: "Ustawa o …"},
+		{name: "diacritics count as one", title: "Ustawa zażółć gęślą", max: 19, want: "Ustawa zażółć gęślą"},
+		{name: "emoji counts as two", title: "📢Obwieszczenie Ministra", max: 20, want: "📢Obwieszczenie …"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			got := shortenTitle(tt.title, tt.max, tweetLength)
+			if got != tt.want {
+				t.Errorf("shortenTitle() = %q, want %q", got, tt.want)
+			}
+			if tweetLength(got) > tt.max {
+				t.Errorf("shortenTitle() = %q is %d characters long", got, tweetLength(got))
+			}
+		})
+	}
+}
+
+func Test_prepareTweetLength(t *testing.T) {
+	t.Parallel()
+	titles := []string{
+		strings.Repeat("Obwieszczenie Ministra Zdrowia w sprawie zażółcenia gęśli ", 10),
+		strings.Repeat("Umowa 🇵🇱 między Rzecząpospolitą Polską a Japonią 日本国 ", 10),
+		strings.Repeat("Rozporządzenie Ministra Finansów, Funduszy i Polityki Regionalnej ", 10),
+	}
+	for _, j := range journals {
+		for _, title := range titles {
+			if got := j.prepareTweet(2026, 0, 12345, title); tweetLength(got) > twitterMaxLength {
+				t.Errorf("prepareTweet() is %d characters long: %s", tweetLength(got), got)
+			}
+		}
+	}
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"context"
+	"os"
+	"sort"
+	"time"
+
+	oldApi "github.com/dghubble/go-twitter/twitter"
+	"github.com/g8rswimmer/go-twitter/v2"
+	log "github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const twitterTarget = "twitter"
+
+// twitterPublisher publishes tweets with the v2 API, media can only be uploaded with the legacy v1.1 API.
+type twitterPublisher struct {
+	client    *twitter.Client
+	old       *oldApi.Client
+	rateLimit rateLimit
+}
+
+func (t *twitterPublisher) Name() string {
+	return twitterTarget
+}
+
+func (t *twitterPublisher) RateLimit() rateLimit {
+	return t.rateLimit
+}
+
+func (t *twitterPublisher) UploadMedia(ctx context.Context, act newAct) ([]string, error) {
+	return uploadImages(ctx, act.Pages, t.old)
+}
+
+func (t *twitterPublisher) Announce(ctx context.Context, act newAct, mediaIDs []string) (string, error) {
+	var media *twitter.CreateTweetMedia
+	if len(mediaIDs) > 0 {
+		media = &twitter.CreateTweetMedia{
+			IDs: mediaIDs,
+		}
+	}
+	return t.createTweet(ctx, twitter.CreateTweetRequest{
+		ForSuperFollowersOnly: false,
+		Text:                  act.journal().prepareTweet(act.Year, act.Nr, act.Pos, act.Title),
+		Media:                 media,
+		Geo: &twitter.CreateTweetGeo{
+			PlaceID: warsaw,
+		},
+	})
+}
+
+func (t *twitterPublisher) Reply(ctx context.Context, parentID, text string) (string, error) {
+	return t.createTweet(ctx, twitter.CreateTweetRequest{
+		ForSuperFollowersOnly: false,
+		Reply: &twitter.CreateTweetReply{
+			InReplyToTweetID: parentID,
+		},
+		Text: text,
+		Geo: &twitter.CreateTweetGeo{
+			PlaceID: warsaw,
+		},
+	})
+}
+
+// createTweet is not retried, a tweet may have been created even when the request failed. The ledger
+// retries it in the next run.
+func (t *twitterPublisher) createTweet(ctx context.Context, tweet twitter.CreateTweetRequest) (string, error) {
+	r, err := t.client.CreateTweet(ctx, tweet)
+	if err != nil {
+		return "", err
+	}
+	if r.RateLimit != nil {
+		t.rateLimit = rateLimit{Limit: r.RateLimit.Limit, Remaining: r.RateLimit.Remaining, Reset: r.RateLimit.Reset.Time()}
+	}
+	log.WithFields(t.rateLimit.fields()).WithField("Text", r.Tweet.Text).Info("Published")
+	return r.Tweet.ID, nil
+}
+
+// Mentions returns tweets mentioning the bot and, when REPLY_SEARCH is set, tweets matching that search query.
+func (t *twitterPublisher) Mentions(ctx context.Context, sinceID string) ([]mention, error) {
+	fields := []twitter.TweetField{twitter.TweetFieldAuthorID, twitter.TweetFieldCreatedAt}
+	timeline, err := t.client.UserMentionTimeline(ctx, userID, twitter.UserMentionTimelineOpts{
+		TweetFields: fields,
+		MaxResults:  100,
+		SinceID:     sinceID,
+	})
+	if err != nil {
+		return nil, err
+	}
+	tweets := timeline.Raw.Tweets
+	if query := os.Getenv("REPLY_SEARCH"); query != "" {
+		search, err := t.client.TweetRecentSearch(ctx, query, twitter.TweetRecentSearchOpts{
+			TweetFields: fields,
+			MaxResults:  100,
+			SinceID:     sinceID,
+		})
+		if err != nil {
+			return nil, err
+		}
+		tweets = append(tweets, search.Raw.Tweets...)
+	}
+
+	seen := map[string]bool{}
+	var mentions []mention
+	for _, tweet := range tweets {
+		if seen[tweet.ID] {
+			continue
+		}
+		seen[tweet.ID] = true
+		created, _ := time.Parse(time.RFC3339, tweet.CreatedAt)
+		mentions = append(mentions, mention{ID: tweet.ID, AuthorID: tweet.AuthorID, Text: tweet.Text, Created: created})
+	}
+	sort.Slice(mentions, func(i, j int) bool { return newerID(mentions[j].ID, mentions[i].ID) })
+	return mentions, nil
+}
+
+// uploadImages uploads pages with the legacy API, it does not take a context so uploads
+// and processing checks are recorded as events of the span in ctx.
+func uploadImages(ctx context.Context, pages [][]byte, client *oldApi.Client) ([]string, error) {
+	span := trace.SpanFromContext(ctx)
+	log.Info("Pages to upload: ", len(pages))
+	mediaIds := make([]string, 0, len(pages))
+	for _, p := range pages {
+		resp, _, err := client.Media.Upload(p, "image/jpeg")
+		if err != nil {
+			return nil, err
+		}
+		mID := resp.MediaIDString
+		span.AddEvent("uploaded", trace.WithAttributes(attribute.String("media.id", mID), attribute.Int("media.bytes", len(p))))
+
+		if resp.ProcessingInfo != nil {
+			log.WithField("MediaID", mID).Debugf("Still processing: %#v", resp.ProcessingInfo)
+			for {
+				time.Sleep(100 * time.Millisecond)
+				log.WithField("MediaID", mID).Debugf("Checking upload status %s", mID)
+				r, _, err := client.Media.Status(resp.MediaID)
+				if err != nil {
+					return nil, err
+				}
+				if r.ProcessingInfo == nil {
+					break
+				}
+				log.WithField("MediaID", mID).Debugf("Still processing: %#v", r.ProcessingInfo)
+				span.AddEvent("processing", trace.WithAttributes(attribute.String("media.id", mID)))
+			}
+		}
+		log.WithField("MediaID", mID).Debug("Upload Succesful")
+		mediaIds = append(mediaIds, mID)
+	}
+	return mediaIds, nil
+}